@@ -0,0 +1,234 @@
+package ngrpc
+
+import (
+	"context"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// PriorityHeader 请求优先级的metadata键，取值 critical、normal、sheddable
+const PriorityHeader = "x-priority"
+
+// Priority 请求优先级，过载时先丢弃低优先级请求
+type Priority int
+
+const (
+	PrioritySheddable Priority = iota
+	PriorityNormal
+	PriorityCritical
+)
+
+// ParsePriority 解析优先级，无法识别时返回 PriorityNormal
+func ParsePriority(priority string) Priority {
+	switch strings.ToLower(priority) {
+	case "critical", "high":
+		return PriorityCritical
+	case "sheddable", "low":
+		return PrioritySheddable
+	}
+	return PriorityNormal
+}
+
+// PriorityFunc 获取请求优先级
+type PriorityFunc func(ctx context.Context, fullMethod string) Priority
+
+// MetadataPriority 从 x-priority 获取优先级，健康检查总是 PriorityCritical；
+// x-priority 由客户端设置，不可信的客户端可以借此在过载时抢占，应只在内部服务间使用或自定义 PriorityFunc
+func MetadataPriority(ctx context.Context, fullMethod string) Priority {
+	if MatchMethod("/grpc.health.v1.Health/*", fullMethod) {
+		return PriorityCritical
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	return ParsePriority(firstMetadata(md, PriorityHeader))
+}
+
+// AdaptiveLimitOptions 自适应并发限制参数，零值使用默认值
+type AdaptiveLimitOptions struct {
+	// InitialLimit 初始并发数，默认20
+	InitialLimit int
+	// MinLimit 最小并发数，默认1
+	MinLimit int
+	// MaxLimit 最大并发数，默认1000
+	MaxLimit int
+	// Tolerance 延迟超过最小延迟的倍数时减小并发数，默认2
+	Tolerance float64
+	// Backoff 减小并发数时的系数，默认0.9
+	Backoff float64
+	// Priority 获取请求优先级，默认 MetadataPriority
+	Priority PriorityFunc
+}
+
+// priorityShare 各优先级可使用的并发比例，critical 允许超出限制
+var priorityShare = [...]float64{
+	PrioritySheddable: 0.75,
+	PriorityNormal:    1,
+	PriorityCritical:  1.25,
+}
+
+// minRTTWindow 最小延迟的统计窗口，过期后重新统计以适应流量变化
+const minRTTWindow = 30 * time.Second
+
+// AdaptiveLimiter AIMD 自适应并发限制：延迟正常时加性增加并发数，延迟升高或超时时乘性减小
+type AdaptiveLimiter struct {
+	opts AdaptiveLimitOptions
+
+	mu         sync.Mutex
+	limit      float64
+	inflight   int
+	minRTT     time.Duration
+	nextMinRTT time.Duration
+	windowEnd  time.Time
+}
+
+// NewAdaptiveLimiter 创建自适应并发限制
+func NewAdaptiveLimiter(opts AdaptiveLimitOptions) *AdaptiveLimiter {
+	if opts.InitialLimit <= 0 {
+		opts.InitialLimit = 20
+	}
+	if opts.MinLimit <= 0 {
+		opts.MinLimit = 1
+	}
+	if opts.MaxLimit <= 0 {
+		opts.MaxLimit = 1000
+	}
+	if opts.Tolerance <= 1 {
+		opts.Tolerance = 2
+	}
+	if opts.Backoff <= 0 || opts.Backoff >= 1 {
+		opts.Backoff = 0.9
+	}
+	if opts.Priority == nil {
+		opts.Priority = MetadataPriority
+	}
+	return &AdaptiveLimiter{
+		opts:  opts,
+		limit: float64(opts.InitialLimit),
+	}
+}
+
+// Limit 当前并发限制
+func (l *AdaptiveLimiter) Limit() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return int(l.limit)
+}
+
+// Inflight 当前并发数
+func (l *AdaptiveLimiter) Inflight() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.inflight
+}
+
+// Acquire 获取执行许可，成功时需在请求结束后调用 release
+func (l *AdaptiveLimiter) Acquire(ctx context.Context, fullMethod string) (release func(err error), ok bool) {
+	if !l.admit(ctx, fullMethod) {
+		return nil, false
+	}
+	start := time.Now()
+	return func(err error) {
+		l.release(time.Since(start), err)
+	}, true
+}
+
+func (l *AdaptiveLimiter) admit(ctx context.Context, fullMethod string) bool {
+	priority := l.opts.Priority(ctx, fullMethod)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.full(priority) {
+		return false
+	}
+	l.inflight++
+	return true
+}
+
+// full 该优先级的请求是否已达到并发限制，调用时需持有 mu
+func (l *AdaptiveLimiter) full(priority Priority) bool {
+	// 自定义 PriorityFunc 可能返回范围之外的值
+	priority = min(max(priority, PrioritySheddable), PriorityCritical)
+	return float64(l.inflight) >= math.Max(1, l.limit*priorityShare[priority])
+}
+
+// overloaded 一元调用的并发是否已达到该请求优先级的限制，不占用并发数
+func (l *AdaptiveLimiter) overloaded(ctx context.Context, fullMethod string) bool {
+	priority := l.opts.Priority(ctx, fullMethod)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.full(priority)
+}
+
+func (l *AdaptiveLimiter) release(rtt time.Duration, err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	inflight := l.inflight
+	l.inflight--
+	switch status.Code(err) {
+	case codes.DeadlineExceeded:
+		l.decrease()
+		return
+	case codes.Canceled, codes.ResourceExhausted:
+		// ResourceExhausted 多为限流拒绝，不代表服务端过载，也不计入延迟
+		return
+	}
+	now := time.Now()
+	if l.nextMinRTT == 0 || rtt < l.nextMinRTT {
+		l.nextMinRTT = rtt
+	}
+	if now.After(l.windowEnd) {
+		l.minRTT, l.nextMinRTT = l.nextMinRTT, 0
+		l.windowEnd = now.Add(minRTTWindow)
+	}
+	if l.minRTT > 0 && float64(rtt) > float64(l.minRTT)*l.opts.Tolerance {
+		l.decrease()
+		return
+	}
+	// 只有接近限制时才增加，避免低负载时限制无限增长
+	if float64(inflight)*2 >= l.limit {
+		l.limit = math.Min(float64(l.opts.MaxLimit), l.limit+1/l.limit)
+	}
+}
+
+func (l *AdaptiveLimiter) decrease() {
+	l.limit = math.Max(float64(l.opts.MinLimit), l.limit*l.opts.Backoff)
+}
+
+// UnaryServerInterceptor 自适应并发限制一元拦截器
+func (l *AdaptiveLimiter) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+		release, ok := l.Acquire(ctx, info.FullMethod)
+		if !ok {
+			return nil, status.Error(codes.Unavailable, "server overloaded")
+		}
+		defer func() {
+			release(err)
+		}()
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor 自适应并发限制流拦截器，一元调用过载时拒绝新的流；
+// 流可能长期存在（如健康检查 Watch），不占用并发数也不计入延迟统计
+func (l *AdaptiveLimiter) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if l.overloaded(stream.Context(), info.FullMethod) {
+			return status.Error(codes.Unavailable, "server overloaded")
+		}
+		return handler(srv, stream)
+	}
+}
+
+// WithServerAdaptiveLimiter 启用自适应并发限制，过载时返回 codes.Unavailable
+func WithServerAdaptiveLimiter(limiter *AdaptiveLimiter) ServerOption {
+	return func(o *ServerOptions) {
+		o.AdaptiveLimiter = limiter
+		o.unaryInterceptors = append(o.unaryInterceptors, limiter.UnaryServerInterceptor())
+		o.streamInterceptors = append(o.streamInterceptors, limiter.StreamServerInterceptor())
+	}
+}
@@ -0,0 +1,84 @@
+package ngrpc
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// fixedPriority 固定优先级，便于测试
+func fixedPriority(priority Priority) PriorityFunc {
+	return func(ctx context.Context, fullMethod string) Priority { return priority }
+}
+
+func TestAdaptiveLimiterPriority(t *testing.T) {
+	limiter := NewAdaptiveLimiter(AdaptiveLimitOptions{InitialLimit: 4, Priority: fixedPriority(PriorityNormal)})
+	ctx := context.Background()
+	for i := 0; i < 4; i++ {
+		if _, ok := limiter.Acquire(ctx, echoUnary); !ok {
+			t.Fatalf("call %d rejected below the limit", i)
+		}
+	}
+	if _, ok := limiter.Acquire(ctx, echoUnary); ok {
+		t.Fatal("normal call admitted above the limit")
+	}
+	// critical 可超出限制 25%
+	limiter.opts.Priority = fixedPriority(PriorityCritical)
+	if _, ok := limiter.Acquire(ctx, echoUnary); !ok {
+		t.Fatal("critical call rejected")
+	}
+	// sheddable 只能使用 75%
+	limiter.opts.Priority = fixedPriority(PrioritySheddable)
+	if limiter.Inflight() != 5 || !limiter.overloaded(ctx, echoUnary) {
+		t.Fatalf("inflight %d, sheddable not overloaded", limiter.Inflight())
+	}
+}
+
+func TestAdaptiveLimiterRelease(t *testing.T) {
+	limiter := NewAdaptiveLimiter(AdaptiveLimitOptions{InitialLimit: 10, Backoff: 0.5})
+	ctx := context.Background()
+
+	release, _ := limiter.Acquire(ctx, echoUnary)
+	release(status.Error(codes.ResourceExhausted, "rate limited"))
+	if got := limiter.Limit(); got != 10 {
+		t.Fatalf("limit after ResourceExhausted = %d, want 10", got)
+	}
+	release, _ = limiter.Acquire(ctx, echoUnary)
+	release(status.Error(codes.DeadlineExceeded, "timeout"))
+	if got := limiter.Limit(); got != 5 {
+		t.Fatalf("limit after DeadlineExceeded = %d, want 5", got)
+	}
+	if got := limiter.Inflight(); got != 0 {
+		t.Fatalf("inflight = %d, want 0", got)
+	}
+}
+
+func TestAdaptiveLimiterStreams(t *testing.T) {
+	limiter := NewAdaptiveLimiter(AdaptiveLimitOptions{InitialLimit: 1, MaxLimit: 1})
+	server := startEchoServer(t, WithServerAdaptiveLimiter(limiter))
+	conn := dialEcho(t, server)
+	desc := &grpc.StreamDesc{StreamName: "ClientStream", ClientStreams: true}
+
+	// 未结束的流不占用并发数，一元调用仍可执行
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stream, err := conn.NewStream(ctx, desc, echoClientStream)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = stream.SendMsg(wrapperspb.String("open")); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if err = conn.Invoke(context.Background(), echoUnary, wrapperspb.String("ping"), wrapperspb.String("")); err != nil {
+			t.Fatalf("unary call %d with an open stream: %v", i, err)
+		}
+	}
+	if got := limiter.Inflight(); got != 0 {
+		t.Fatalf("inflight = %d, want 0", got)
+	}
+}
@@ -0,0 +1,328 @@
+package ngrpc
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/http/pprof"
+	"sort"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"google.golang.org/grpc"
+	channelz "google.golang.org/grpc/channelz/service"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	reflectionalphapb "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+)
+
+// WithServerReflection 是否在主监听上注册反射服务，默认注册
+func WithServerReflection(reflection bool) ServerOption {
+	return func(o *ServerOptions) {
+		o.Reflection = reflection
+	}
+}
+
+// WithServerAdmin 在 address 上提供管理服务：
+// gRPC 反射（反射主服务）、channelz、健康检查，HTTP /debug/pprof/ 和 /debug/ngrpc；
+// 管理服务不加密、不注册到注册中心，应只监听内网或本机地址
+func WithServerAdmin(address string) ServerOption {
+	return func(o *ServerOptions) {
+		o.AdminAddress = address
+	}
+}
+
+// adminListeners 创建管理服务监听
+func (s *GrpcServer) adminListeners() (listeners []*serverListener, err error) {
+	if s.opts.AdminAddress == "" {
+		return
+	}
+	lis, err := Listen(s.opts.AdminAddress, s.opts.SocketMode)
+	if err != nil {
+		return nil, fmt.Errorf("listen admin %s: %w", s.opts.AdminAddress, err)
+	}
+	admin := grpc.NewServer()
+	reflectionOptions := reflection.ServerOptions{Services: s.server}
+	reflectionpb.RegisterServerReflectionServer(admin, reflection.NewServerV1(reflectionOptions))
+	reflectionalphapb.RegisterServerReflectionServer(admin, reflection.NewServer(reflectionOptions))
+	channelz.RegisterChannelzServiceToServer(admin)
+	healthpb.RegisterHealthServer(admin, s.health)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.HandleFunc("/debug/ngrpc", s.serveDebugInfo)
+
+	s.mu.Lock()
+	s.adminServer = admin
+	s.mu.Unlock()
+	listeners = append(listeners, &serverListener{
+		Listener: lis,
+		config:   ServerListener{Address: s.opts.AdminAddress, NoRegister: true},
+		handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if isGrpcRequest(r) {
+				admin.ServeHTTP(w, r)
+				return
+			}
+			mux.ServeHTTP(w, r)
+		}),
+	})
+	return
+}
+
+// setServing 设置健康检查状态，空服务名和全部已注册服务
+func (s *GrpcServer) setServing(serving bool) {
+	st := healthpb.HealthCheckResponse_NOT_SERVING
+	if serving {
+		st = healthpb.HealthCheckResponse_SERVING
+	}
+	s.health.SetServingStatus("", st)
+	for name := range s.server.GetServiceInfo() {
+		s.health.SetServingStatus(name, st)
+	}
+}
+
+// closeAdmin 关闭管理服务
+func (s *GrpcServer) closeAdmin() {
+	s.mu.Lock()
+	admin := s.adminServer
+	s.adminServer = nil
+	s.mu.Unlock()
+	if admin != nil {
+		admin.Stop()
+	}
+}
+
+// DebugInfo /debug/ngrpc 展示的运行时信息，包括生效的连接参数和限流状态
+type DebugInfo struct {
+	Options   DebugOptions   `json:"options"`
+	Transport DebugTransport `json:"transport"`
+	Limits    DebugLimits    `json:"limits"`
+	Services  []DebugService `json:"services"`
+	Registry  *DebugRegistry `json:"registry,omitempty"`
+	StartedAt time.Time      `json:"started_at,omitempty"`
+}
+
+// DebugOptions 服务端配置
+type DebugOptions struct {
+	Name           string   `json:"name"`
+	Address        string   `json:"address"`
+	RandomPort     bool     `json:"random_port"`
+	Listeners      []string `json:"listeners,omitempty"`
+	Credentials    string   `json:"credentials"`
+	Reflection     bool     `json:"reflection"`
+	Tracing        bool     `json:"tracing"`
+	Metrics        bool     `json:"metrics"`
+	RateLimit      bool     `json:"rate_limit"`
+	HTTPHandler    bool     `json:"http_handler"`
+	Web            bool     `json:"web"`
+	GatewayAddress string   `json:"gateway_address,omitempty"`
+	AdminAddress   string   `json:"admin_address,omitempty"`
+	// UnaryInterceptors、StreamInterceptors 内置和用户拦截器数，不包括追踪和方法白名单
+	UnaryInterceptors  int `json:"unary_interceptors"`
+	StreamInterceptors int `json:"stream_interceptors"`
+}
+
+// DebugTransport 生效的连接参数，时长为零表示未设置，消息大小为零表示使用 grpc 默认值
+type DebugTransport struct {
+	MaxConnectionIdle     string `json:"max_connection_idle"`
+	MaxConnectionAge      string `json:"max_connection_age"`
+	MaxConnectionAgeGrace string `json:"max_connection_age_grace"`
+	KeepaliveTime         string `json:"keepalive_time"`
+	KeepaliveTimeout      string `json:"keepalive_timeout"`
+	PolicyMinTime         string `json:"policy_min_time"`
+	PolicyWithoutStream   bool   `json:"policy_permit_without_stream"`
+	MaxRecvMsgSize        int    `json:"max_recv_msg_size"`
+	MaxSendMsgSize        int    `json:"max_send_msg_size"`
+	MaxConcurrentStreams  uint32 `json:"max_concurrent_streams"`
+	DeadlineMax           string `json:"deadline_max,omitempty"`
+	DeadlineMinRemaining  string `json:"deadline_min_remaining,omitempty"`
+}
+
+// DebugLimits 限流和并发限制的当前状态，未启用时为nil
+type DebugLimits struct {
+	RateLimit *DebugRateLimit     `json:"rate_limit,omitempty"`
+	Adaptive  *DebugAdaptiveLimit `json:"adaptive,omitempty"`
+}
+
+// DebugRateLimit 当前限流配置和单独限流的客户端数
+type DebugRateLimit struct {
+	RateLimitConfig
+	Clients int `json:"clients"`
+}
+
+// DebugAdaptiveLimit 自适应并发限制的当前值
+type DebugAdaptiveLimit struct {
+	Limit    int `json:"limit"`
+	Inflight int `json:"inflight"`
+}
+
+// DebugService 已注册的服务
+type DebugService struct {
+	Name    string   `json:"name"`
+	Methods []string `json:"methods"`
+}
+
+// DebugRegistry 注册中心状态
+type DebugRegistry struct {
+	Type      string   `json:"type"`
+	Domain    string   `json:"domain,omitempty"`
+	Addresses []string `json:"addresses,omitempty"`
+	LeaseIDs  []string `json:"lease_ids,omitempty"`
+}
+
+// DebugInfo 获取运行时信息
+func (s *GrpcServer) DebugInfo() DebugInfo {
+	o := s.opts
+	info := DebugInfo{
+		Options: DebugOptions{
+			Name:               o.Name,
+			Address:            o.Address,
+			RandomPort:         o.RandomPort,
+			Credentials:        "insecure",
+			Reflection:         o.Reflection,
+			Tracing:            o.TracerProvider != nil,
+			Metrics:            o.MeterProvider != nil,
+			RateLimit:          o.RateLimiter != nil,
+			HTTPHandler:        o.HTTPHandler != nil,
+			Web:                o.Web != nil,
+			GatewayAddress:     o.GatewayAddress,
+			AdminAddress:       o.AdminAddress,
+			UnaryInterceptors:  len(o.unaryInterceptors) + len(o.UnaryServerInterceptors),
+			StreamInterceptors: len(o.streamInterceptors) + len(o.StreamServerInterceptors),
+		},
+		Transport: DebugTransport{
+			MaxConnectionIdle:     o.Keepalive.MaxConnectionIdle.String(),
+			MaxConnectionAge:      o.Keepalive.MaxConnectionAge.String(),
+			MaxConnectionAgeGrace: o.Keepalive.MaxConnectionAgeGrace.String(),
+			KeepaliveTime:         o.Keepalive.Time.String(),
+			KeepaliveTimeout:      o.Keepalive.Timeout.String(),
+			PolicyMinTime:         o.KeepalivePolicy.MinTime.String(),
+			PolicyWithoutStream:   o.KeepalivePolicy.PermitWithoutStream,
+			MaxRecvMsgSize:        o.MaxRecvMsgSize,
+			MaxSendMsgSize:        o.MaxSendMsgSize,
+			MaxConcurrentStreams:  o.MaxConcurrentStreams,
+		},
+	}
+	if o.DeadlineLimit != nil {
+		info.Transport.DeadlineMax = o.DeadlineLimit.Max.String()
+		info.Transport.DeadlineMinRemaining = o.DeadlineLimit.MinRemaining.String()
+	}
+	if o.RateLimiter != nil {
+		info.Limits.RateLimit = &DebugRateLimit{RateLimitConfig: o.RateLimiter.Config(), Clients: o.RateLimiter.clientCount()}
+	}
+	if o.AdaptiveLimiter != nil {
+		info.Limits.Adaptive = &DebugAdaptiveLimit{Limit: o.AdaptiveLimiter.Limit(), Inflight: o.AdaptiveLimiter.Inflight()}
+	}
+	if o.Credentials != nil {
+		info.Options.Credentials = o.Credentials.Info().SecurityProtocol
+	}
+	for _, lis := range o.Listeners {
+		info.Options.Listeners = append(info.Options.Listeners, lis.Address)
+	}
+	for name, service := range s.server.GetServiceInfo() {
+		ds := DebugService{Name: name}
+		for _, method := range service.Methods {
+			ds.Methods = append(ds.Methods, method.Name)
+		}
+		info.Services = append(info.Services, ds)
+	}
+	sort.Slice(info.Services, func(i, j int) bool { return info.Services[i].Name < info.Services[j].Name })
+
+	s.mu.Lock()
+	info.StartedAt = s.startedAt
+	registered := s.registered
+	s.mu.Unlock()
+	if o.register != nil {
+		registry := &DebugRegistry{Type: fmt.Sprintf("%T", o.register)}
+		if r, ok := o.register.(interface{ Domain() string }); ok {
+			registry.Domain = r.Domain()
+		}
+		if r, ok := o.register.(interface{ LeaseIDs() []clientv3.LeaseID }); ok {
+			for _, id := range r.LeaseIDs() {
+				registry.LeaseIDs = append(registry.LeaseIDs, fmt.Sprintf("%x", int64(id)))
+			}
+		}
+		for _, serviceInfo := range registered {
+			registry.Addresses = append(registry.Addresses, serviceInfo.Address)
+		}
+		info.Registry = registry
+	}
+	return info
+}
+
+var debugTemplate = template.Must(template.New("ngrpc").Parse(`<!DOCTYPE html>
+<html><head><title>ngrpc {{.Options.Name}}</title></head>
+<body>
+<h1>{{.Options.Name}}</h1>
+<p>started at {{.StartedAt}} · <a href="?format=json">json</a> · <a href="/debug/pprof/">pprof</a></p>
+<h2>Options</h2>
+<table>
+<tr><td>address</td><td>{{.Options.Address}}{{if .Options.RandomPort}} (random port){{end}}</td></tr>
+{{range .Options.Listeners}}<tr><td>listener</td><td>{{.}}</td></tr>{{end}}
+<tr><td>credentials</td><td>{{.Options.Credentials}}</td></tr>
+<tr><td>reflection</td><td>{{.Options.Reflection}}</td></tr>
+<tr><td>tracing / metrics</td><td>{{.Options.Tracing}} / {{.Options.Metrics}}</td></tr>
+<tr><td>rate limit</td><td>{{.Options.RateLimit}}</td></tr>
+<tr><td>http handler / web</td><td>{{.Options.HTTPHandler}} / {{.Options.Web}}</td></tr>
+<tr><td>gateway</td><td>{{.Options.GatewayAddress}}</td></tr>
+<tr><td>admin</td><td>{{.Options.AdminAddress}}</td></tr>
+<tr><td>interceptors (unary / stream)</td><td>{{.Options.UnaryInterceptors}} / {{.Options.StreamInterceptors}}</td></tr>
+</table>
+<h2>Transport</h2>
+{{with .Transport}}<table>
+<tr><td>max connection idle</td><td>{{.MaxConnectionIdle}}</td></tr>
+<tr><td>max connection age / grace</td><td>{{.MaxConnectionAge}} / {{.MaxConnectionAgeGrace}}</td></tr>
+<tr><td>keepalive time / timeout</td><td>{{.KeepaliveTime}} / {{.KeepaliveTimeout}}</td></tr>
+<tr><td>keepalive policy min time / without stream</td><td>{{.PolicyMinTime}} / {{.PolicyWithoutStream}}</td></tr>
+<tr><td>max recv / send msg size</td><td>{{.MaxRecvMsgSize}} / {{.MaxSendMsgSize}}</td></tr>
+<tr><td>max concurrent streams</td><td>{{.MaxConcurrentStreams}}</td></tr>
+{{if .DeadlineMax}}<tr><td>deadline max / min remaining</td><td>{{.DeadlineMax}} / {{.DeadlineMinRemaining}}</td></tr>{{end}}
+</table>{{end}}
+<h2>Limits</h2>
+<table>
+{{with .Limits.RateLimit}}<tr><td>rate limit global</td><td>{{.Global.Rate}}/s burst {{.Global.Burst}}</td></tr>
+<tr><td>rate limit per client</td><td>{{.PerClient.Rate}}/s burst {{.PerClient.Burst}}, {{.Clients}} of {{.MaxClients}} clients</td></tr>
+{{range $method, $limit := .Methods}}<tr><td>rate limit {{$method}}</td><td>{{$limit.Rate}}/s burst {{$limit.Burst}}</td></tr>{{end}}{{end}}
+{{with .Limits.Adaptive}}<tr><td>adaptive limit / inflight</td><td>{{.Limit}} / {{.Inflight}}</td></tr>{{end}}
+</table>
+<h2>Services</h2>
+<ul>{{range .Services}}<li>{{.Name}}<ul>{{range .Methods}}<li>{{.}}</li>{{end}}</ul></li>{{end}}</ul>
+<h2>Registry</h2>
+{{with .Registry}}<table>
+<tr><td>type</td><td>{{.Type}}</td></tr>
+<tr><td>domain</td><td>{{.Domain}}</td></tr>
+<tr><td>addresses</td><td>{{range .Addresses}}{{.}} {{end}}</td></tr>
+<tr><td>lease ids</td><td>{{range .LeaseIDs}}{{.}} {{end}}</td></tr>
+</table>{{else}}<p>not registered</p>{{end}}
+</body></html>
+`))
+
+// serveDebugInfo 输出运行时信息，format=json 时输出 JSON
+func (s *GrpcServer) serveDebugInfo(w http.ResponseWriter, r *http.Request) {
+	info := s.DebugInfo()
+	if r.URL.Query().Get("format") == "json" {
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.Encode(info)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := debugTemplate.Execute(w, info); err != nil {
+		s.opts.Log.Errorf(s.ctx, "%s debug page: %v", s.opts.Name, err)
+	}
+}
+
+// newHealthServer 健康检查服务，服务开始前为 NOT_SERVING
+func newHealthServer() *health.Server {
+	server := health.NewServer()
+	server.SetServingStatus("", healthpb.HealthCheckResponse_NOT_SERVING)
+	return server
+}
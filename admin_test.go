@@ -0,0 +1,101 @@
+package ngrpc
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/keepalive"
+)
+
+func TestAdmin(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "admin.sock")
+	limiter := NewRateLimiter(RateLimitConfig{Global: RateLimit{Rate: 100, Burst: 10}, MaxClients: 5})
+	startEchoServer(t,
+		WithServerAdmin(unixScheme+"//"+socket),
+		WithServerKeepalive(keepalive.ServerParameters{MaxConnectionIdle: time.Minute}),
+		WithServerMaxRecvMsgSize(1<<20),
+		WithServerMaxConcurrentStreams(100),
+		WithServerRateLimiter(limiter),
+		WithServerAdaptiveLimiter(NewAdaptiveLimiter(AdaptiveLimitOptions{InitialLimit: 20})),
+		WithServerDeadlineLimit(DeadlineLimit{Max: 10 * time.Second}),
+	)
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return new(net.Dialer).DialContext(ctx, "unix", socket)
+		},
+	}}
+	t.Cleanup(client.CloseIdleConnections)
+	get := func(path string) string {
+		resp, err := client.Get("http://admin" + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("GET %s: status %d: %s", path, resp.StatusCode, body)
+		}
+		return string(body)
+	}
+
+	var info DebugInfo
+	if err := json.Unmarshal([]byte(get("/debug/ngrpc?format=json")), &info); err != nil {
+		t.Fatal(err)
+	}
+	if info.Transport.MaxConnectionIdle != "1m0s" || info.Transport.MaxRecvMsgSize != 1<<20 ||
+		info.Transport.MaxConcurrentStreams != 100 || info.Transport.DeadlineMax != "10s" {
+		t.Fatalf("transport = %+v", info.Transport)
+	}
+	if rl := info.Limits.RateLimit; rl == nil || rl.Global.Rate != 100 || rl.MaxClients != 5 {
+		t.Fatalf("rate limit = %+v", rl)
+	}
+	if a := info.Limits.Adaptive; a == nil || a.Limit != 20 || a.Inflight != 0 {
+		t.Fatalf("adaptive = %+v", a)
+	}
+	// 限流、自适应限制和 deadline 限制各有一元和流拦截器
+	if info.Options.UnaryInterceptors != 3 || info.Options.StreamInterceptors != 3 {
+		t.Fatalf("interceptors = %d / %d, want 3 / 3", info.Options.UnaryInterceptors, info.Options.StreamInterceptors)
+	}
+	if n := len(info.Services); n == 0 || info.Services[n-1].Name != echoService {
+		t.Fatalf("services = %+v", info.Services)
+	}
+	if info.StartedAt.IsZero() {
+		t.Fatal("started at not set")
+	}
+	page := get("/debug/ngrpc")
+	for _, want := range []string{"<h2>Transport</h2>", "1m0s", "adaptive limit / inflight", echoService} {
+		if !strings.Contains(page, want) {
+			t.Fatalf("debug page missing %q", want)
+		}
+	}
+	if !strings.Contains(get("/debug/pprof/"), "goroutine") {
+		t.Fatal("pprof index not served")
+	}
+
+	// 同一地址上的 gRPC 健康检查
+	conn, err := grpc.NewClient("unix://"+socket, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	resp, err := healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{Service: echoService})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+		t.Fatalf("health = %s, want SERVING", resp.GetStatus())
+	}
+}
@@ -0,0 +1,110 @@
+package ngrpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gopkg.in/yaml.v3"
+)
+
+// WithServerMethodAllowList 只允许调用匹配的方法，支持 /pkg.Service/* 形式，
+// DefaultAuthSkipMethods 中的健康检查和反射始终允许；可通过 GrpcServer.SetMethodAllowList 在运行时替换
+func WithServerMethodAllowList(methods ...string) ServerOption {
+	return func(o *ServerOptions) {
+		o.AllowedMethods = append([]string{}, methods...)
+	}
+}
+
+// SetMethodAllowList 运行时替换允许调用的方法，nil 表示允许全部方法
+func (s *GrpcServer) SetMethodAllowList(methods []string) {
+	if methods == nil {
+		s.allowedMethods.Store(nil)
+		return
+	}
+	methods = append([]string{}, methods...)
+	s.allowedMethods.Store(&methods)
+}
+
+// MethodAllowList 当前允许调用的方法，nil 表示允许全部方法
+func (s *GrpcServer) MethodAllowList() []string {
+	if methods := s.allowedMethods.Load(); methods != nil {
+		return append([]string{}, *methods...)
+	}
+	return nil
+}
+
+func (s *GrpcServer) methodAllowed(fullMethod string) error {
+	methods := s.allowedMethods.Load()
+	if methods == nil || matchAnyMethod(*methods, fullMethod) || matchAnyMethod(DefaultAuthSkipMethods, fullMethod) {
+		return nil
+	}
+	return status.Errorf(codes.PermissionDenied, "method %s is not allowed", fullMethod)
+}
+
+func (s *GrpcServer) allowListUnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if err := s.methodAllowed(info.FullMethod); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+func (s *GrpcServer) allowListStreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := s.methodAllowed(info.FullMethod); err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+}
+
+// LoadMethodAllowList 从YAML或JSON文件加载允许调用的方法列表
+func LoadMethodAllowList(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseMethodAllowList(data)
+}
+
+// ParseMethodAllowList 解析YAML或JSON格式的方法列表，如 ["/pkg.Service/*"]，空列表表示只允许健康检查和反射
+func ParseMethodAllowList(data []byte) ([]string, error) {
+	var methods []string
+	if err := yaml.Unmarshal(data, &methods); err != nil {
+		return nil, fmt.Errorf("method allow list: %w", err)
+	}
+	if methods == nil {
+		return nil, errors.New("method allow list: empty document")
+	}
+	for _, method := range methods {
+		if !strings.HasPrefix(method, "/") {
+			return nil, fmt.Errorf("method allow list: method %q must be a full method name like /pkg.Service/Method", method)
+		}
+	}
+	return methods, nil
+}
+
+// WatchMethodAllowList 监听方法列表的变化并替换允许调用的方法，解析失败时保留原列表，阻塞直到 ctx 取消
+func (s *GrpcServer) WatchMethodAllowList(ctx context.Context, source ConfigSource) error {
+	return source.Watch(ctx, func(data []byte, err error) {
+		var methods []string
+		if err == nil {
+			if methods, err = ParseMethodAllowList(data); err == nil {
+				if !reflect.DeepEqual(methods, s.MethodAllowList()) {
+					s.SetMethodAllowList(methods)
+					s.opts.Log.Infof(ctx, "%s method allow list set to %v", s.opts.Name, methods)
+				}
+				return
+			}
+		}
+		s.opts.Log.Errorf(ctx, "%s method allow list reload failed, keeping previous list: %v", s.opts.Name, err)
+	})
+}
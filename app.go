@@ -0,0 +1,238 @@
+package ngrpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// Component 随 App 启停的组件
+type Component interface {
+	// Start 启动组件，返回错误时 App 停止已启动的部分并退出
+	Start(ctx context.Context) error
+	// Stop 停止组件，ctx 为关闭超时
+	Stop(ctx context.Context) error
+}
+
+// Hook 由函数组成的组件，未设置的函数不执行
+type Hook struct {
+	OnStart func(ctx context.Context) error
+	OnStop  func(ctx context.Context) error
+}
+
+func (h Hook) Start(ctx context.Context) error {
+	if h.OnStart == nil {
+		return nil
+	}
+	return h.OnStart(ctx)
+}
+
+func (h Hook) Stop(ctx context.Context) error {
+	if h.OnStop == nil {
+		return nil
+	}
+	return h.OnStop(ctx)
+}
+
+// AppOptions App 可选参数
+type AppOptions struct {
+	Name            string
+	Log             Logger
+	ShutdownTimeout time.Duration
+	Signals         []os.Signal
+}
+
+// AppOption 为 App 可选参数赋值的函数
+type AppOption func(*AppOptions)
+
+// WithAppName 应用名称，用于日志
+func WithAppName(name string) AppOption {
+	return func(o *AppOptions) {
+		o.Name = name
+	}
+}
+
+// WithAppLogger 设置日志
+func WithAppLogger(log Logger) AppOption {
+	return func(o *AppOptions) {
+		o.Log = log
+	}
+}
+
+// WithAppShutdownTimeout 关闭超时，默认30秒，超时后强制停止服务端
+func WithAppShutdownTimeout(timeout time.Duration) AppOption {
+	return func(o *AppOptions) {
+		o.ShutdownTimeout = timeout
+	}
+}
+
+// WithAppSignals 触发关闭的信号，默认 SIGINT 和 SIGTERM
+func WithAppSignals(signals ...os.Signal) AppOption {
+	return func(o *AppOptions) {
+		o.Signals = signals
+	}
+}
+
+// App 管理服务端、客户端、注册中心连接和其他组件的生命周期
+type App struct {
+	opts       AppOptions
+	servers    []*GrpcServer
+	clients    []*GrpcClient
+	closers    []io.Closer
+	components []Component
+}
+
+// NewApp 创建应用
+func NewApp(opts ...AppOption) *App {
+	app := &App{
+		opts: AppOptions{
+			Name:            "ngrpc",
+			Log:             NewStdLogger(LogLevelDebug, nil, false),
+			ShutdownTimeout: 30 * time.Second,
+			Signals:         []os.Signal{os.Interrupt, syscall.SIGTERM},
+		},
+	}
+	for _, o := range opts {
+		o(&app.opts)
+	}
+	return app
+}
+
+// AddServer 添加服务端，Run 时启动，关闭时先注销再优雅停止
+func (a *App) AddServer(servers ...*GrpcServer) *App {
+	a.servers = append(a.servers, servers...)
+	return a
+}
+
+// AddClient 添加客户端，服务端和组件停止后关闭
+func (a *App) AddClient(clients ...*GrpcClient) *App {
+	a.clients = append(a.clients, clients...)
+	return a
+}
+
+// AddCloser 添加最后关闭的资源，如 etcd 客户端
+func (a *App) AddCloser(closers ...io.Closer) *App {
+	a.closers = append(a.closers, closers...)
+	return a
+}
+
+// AddComponent 添加组件，按添加顺序在服务端之前启动，在服务端停止后按相反顺序停止
+func (a *App) AddComponent(components ...Component) *App {
+	a.components = append(a.components, components...)
+	return a
+}
+
+// Run 启动全部组件和服务端，阻塞直到 ctx 取消、收到信号或任一服务端退出，
+// 然后依次注销服务、优雅停止服务端、停止组件、关闭客户端和注册中心连接，返回汇总的错误；
+// 关闭时先等待启动中的服务端完成注册，返回前等待全部服务端的 Run 返回；
+// 组件启动失败时不启动服务端，只停止已启动的组件
+func (a *App) Run(ctx context.Context) error {
+	ctx, stop := signal.NotifyContext(ctx, a.opts.Signals...)
+	defer stop()
+
+	var errs []error
+	started := 0
+	for _, component := range a.components {
+		if err := component.Start(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("start component %T: %w", component, err))
+			break
+		}
+		started++
+	}
+	var servers []*GrpcServer
+	exited := make(chan error, len(a.servers))
+	running := 0
+	if len(errs) == 0 {
+		servers = a.servers
+		running = len(servers)
+		for _, server := range servers {
+			go func(server *GrpcServer) {
+				// 在别处被停止时 Run 返回 nil，同样触发关闭
+				err := server.Run()
+				if err != nil {
+					err = fmt.Errorf("%s grpc server: %w", server.opts.Name, err)
+				}
+				exited <- err
+			}(server)
+		}
+		select {
+		case <-ctx.Done():
+			a.opts.Log.Infof(ctx, "%s shutting down", a.opts.Name)
+		case err := <-exited:
+			running--
+			if err == nil {
+				a.opts.Log.Infof(ctx, "%s shutting down: grpc server stopped", a.opts.Name)
+			} else {
+				a.opts.Log.Errorf(ctx, "%s shutting down: %v", a.opts.Name, err)
+				errs = append(errs, err)
+			}
+		}
+	}
+	// 关闭不受已取消的 ctx 影响
+	shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), a.opts.ShutdownTimeout)
+	defer cancel()
+	errs = append(errs, a.shutdown(shutdownCtx, servers, a.components[:started]))
+	// 等待其余服务端的 Run 返回
+	for ; running > 0; running-- {
+		select {
+		case err := <-exited:
+			errs = append(errs, err)
+		case <-shutdownCtx.Done():
+			errs = append(errs, fmt.Errorf("%s: %d grpc servers did not stop: %w", a.opts.Name, running, shutdownCtx.Err()))
+			return errors.Join(errs...)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// shutdown 按 注销 → 优雅停止 → 停止组件 → 关闭客户端 → 关闭注册中心连接 的顺序关闭
+func (a *App) shutdown(ctx context.Context, servers []*GrpcServer, components []Component) error {
+	var errs []error
+	// 等待启动完成或失败，避免注销之后服务端才注册
+	for _, server := range servers {
+		select {
+		case <-server.Ready():
+		case <-server.Done():
+		case <-ctx.Done():
+		}
+	}
+	for _, server := range servers {
+		if err := server.Deregister(); err != nil {
+			errs = append(errs, fmt.Errorf("%s deregister: %w", server.opts.Name, err))
+		}
+	}
+	drained := make(chan error, len(servers))
+	for _, server := range servers {
+		go func(server *GrpcServer) {
+			drained <- server.GracefulStop(ctx)
+		}(server)
+	}
+	for range servers {
+		errs = append(errs, <-drained)
+	}
+	for i := len(components) - 1; i >= 0; i-- {
+		if err := components[i].Stop(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("stop component %T: %w", components[i], err))
+		}
+	}
+	for _, client := range a.clients {
+		if err := client.close(); err != nil {
+			errs = append(errs, fmt.Errorf("%s grpc client close: %w", client.opts.Name, err))
+		}
+	}
+	for _, closer := range a.closers {
+		if err := closer.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("close %T: %w", closer, err))
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		a.opts.Log.Errorf(ctx, "%s shutdown: %v", a.opts.Name, err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,220 @@
+package ngrpc
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nilorg/ngrpc/v2/resolver"
+)
+
+// appEvents 按顺序记录启停事件
+type appEvents struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (e *appEvents) add(event string) {
+	e.mu.Lock()
+	e.events = append(e.events, event)
+	e.mu.Unlock()
+}
+
+func (e *appEvents) list() []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]string(nil), e.events...)
+}
+
+// hook 记录启停事件的组件，返回给定的错误
+func (e *appEvents) hook(name string, startErr, stopErr error) Hook {
+	return Hook{
+		OnStart: func(ctx context.Context) error {
+			e.add("start " + name)
+			return startErr
+		},
+		OnStop: func(ctx context.Context) error {
+			e.add("stop " + name)
+			return stopErr
+		},
+	}
+}
+
+// eventRegistry 记录注销事件的注册中心
+type eventRegistry struct {
+	events *appEvents
+}
+
+func (r eventRegistry) Register(serviceInfo *resolver.ServiceInfo) error { return nil }
+
+func (r eventRegistry) Close() error {
+	r.events.add("deregister")
+	return nil
+}
+
+// eventCloser 记录关闭事件，返回给定的错误
+type eventCloser struct {
+	events *appEvents
+	err    error
+}
+
+func (c eventCloser) Close() error {
+	c.events.add("close")
+	return c.err
+}
+
+func newAppTestServer(events *appEvents) *GrpcServer {
+	return NewGrpcServer(context.Background(),
+		WithServerAddress("127.0.0.1:0"),
+		WithServerLogger(nopLogger{}),
+		WithServerRegister(eventRegistry{events: events}),
+	)
+}
+
+func TestAppShutdownOrder(t *testing.T) {
+	events := new(appEvents)
+	server := newAppTestServer(events)
+	stopErr := errors.New("stop b")
+	closeErr := errors.New("close")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	app := NewApp(WithAppLogger(nopLogger{}), WithAppShutdownTimeout(5*time.Second)).
+		AddComponent(events.hook("a", nil, nil), events.hook("b", nil, stopErr)).
+		AddServer(server).
+		AddCloser(eventCloser{events: events, err: closeErr})
+	result := make(chan error, 1)
+	go func() {
+		result <- app.Run(ctx)
+	}()
+	select {
+	case <-server.Ready():
+	case <-time.After(5 * time.Second):
+		t.Fatal("server not ready")
+	}
+	events.add("cancel")
+	cancel()
+	var err error
+	select {
+	case err = <-result:
+	case <-time.After(5 * time.Second):
+		t.Fatal("app did not stop")
+	}
+	want := []string{"start a", "start b", "cancel", "deregister", "stop b", "stop a", "close"}
+	if got := events.list(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("events = %q, want %q", got, want)
+	}
+	if !errors.Is(err, stopErr) || !errors.Is(err, closeErr) {
+		t.Fatalf("err = %v, want both %v and %v", err, stopErr, closeErr)
+	}
+}
+
+func TestAppComponentStartFailure(t *testing.T) {
+	events := new(appEvents)
+	server := newAppTestServer(events)
+	startErr := errors.New("start b")
+	app := NewApp(WithAppLogger(nopLogger{})).
+		AddComponent(events.hook("a", nil, nil), events.hook("b", startErr, nil), events.hook("c", nil, nil)).
+		AddServer(server)
+	err := app.Run(context.Background())
+	if !errors.Is(err, startErr) {
+		t.Fatalf("err = %v, want %v", err, startErr)
+	}
+	// 服务端未启动，不注销也不停止
+	want := []string{"start a", "start b", "stop a"}
+	if got := events.list(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("events = %q, want %q", got, want)
+	}
+	if server.running.Load() {
+		t.Fatal("server was started")
+	}
+}
+
+func TestAppServerStopped(t *testing.T) {
+	events := new(appEvents)
+	server := newAppTestServer(events)
+	app := NewApp(WithAppLogger(nopLogger{})).AddServer(server)
+	result := make(chan error, 1)
+	go func() {
+		result <- app.Run(context.Background())
+	}()
+	<-server.Ready()
+	// 在别处停止服务端，Run 返回 nil 后 App 同样关闭
+	server.Stop()
+	select {
+	case err := <-result:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("app did not stop")
+	}
+}
+
+// blockingRegistry 注册阻塞到 release 关闭，用于模拟启动中的服务端
+type blockingRegistry struct {
+	events   *appEvents
+	started  chan struct{}
+	release  chan struct{}
+	startOne sync.Once
+}
+
+func (r *blockingRegistry) Register(serviceInfo *resolver.ServiceInfo) error {
+	r.startOne.Do(func() { close(r.started) })
+	<-r.release
+	r.events.add("register")
+	return nil
+}
+
+func (r *blockingRegistry) Close() error {
+	r.events.add("deregister")
+	return nil
+}
+
+func TestAppShutdownDuringStartup(t *testing.T) {
+	events := new(appEvents)
+	registry := &blockingRegistry{events: events, started: make(chan struct{}), release: make(chan struct{})}
+	server := NewGrpcServer(context.Background(),
+		WithServerAddress("127.0.0.1:0"),
+		WithServerLogger(nopLogger{}),
+		WithServerRegister(registry),
+	)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	app := NewApp(WithAppLogger(nopLogger{}), WithAppShutdownTimeout(5*time.Second)).AddServer(server)
+	result := make(chan error, 1)
+	go func() {
+		result <- app.Run(ctx)
+	}()
+	select {
+	case <-registry.started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("server did not start registering")
+	}
+	cancel()
+	// 注册完成之前不注销也不返回
+	select {
+	case err := <-result:
+		t.Fatalf("app returned during registration: %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+	close(registry.release)
+	select {
+	case err := <-result:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("app did not stop")
+	}
+	if want, got := []string{"register", "deregister"}, events.list(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("events = %q, want %q", got, want)
+	}
+	select {
+	case <-server.Done():
+	default:
+		t.Fatal("app returned before server Run")
+	}
+}
@@ -0,0 +1,173 @@
+package ngrpc
+
+import (
+	"context"
+	"os"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"gopkg.in/yaml.v3"
+)
+
+// APIKeyHeader API key 的metadata键
+const APIKeyHeader = "x-api-key"
+
+type subjectKey struct{}
+
+// ContextWithSubject 将认证主体放入上下文
+func ContextWithSubject(ctx context.Context, subject string) context.Context {
+	return WithLogFields(context.WithValue(ctx, subjectKey{}, subject), "subject", subject)
+}
+
+// SubjectFromContext 获取认证主体
+func SubjectFromContext(ctx context.Context) (subject string, ok bool) {
+	subject, ok = ctx.Value(subjectKey{}).(string)
+	return
+}
+
+// KeyStore API key 存储，返回 key 对应的主体
+type KeyStore interface {
+	Lookup(ctx context.Context, key string) (subject string, ok bool, err error)
+}
+
+// KeyStoreFunc 函数形式的 KeyStore
+type KeyStoreFunc func(ctx context.Context, key string) (subject string, ok bool, err error)
+
+// Lookup 查找 key
+func (f KeyStoreFunc) Lookup(ctx context.Context, key string) (string, bool, error) {
+	return f(ctx, key)
+}
+
+// MemoryKeyStore 内存 KeyStore
+type MemoryKeyStore struct {
+	mu   sync.RWMutex
+	keys map[string]string
+}
+
+// NewMemoryKeyStore 创建内存 KeyStore，keys 为 key 到主体的映射
+func NewMemoryKeyStore(keys map[string]string) *MemoryKeyStore {
+	s := &MemoryKeyStore{keys: make(map[string]string, len(keys))}
+	for k, v := range keys {
+		s.keys[k] = v
+	}
+	return s
+}
+
+// LoadFileKeyStore 从YAML或JSON文件加载 key 到主体的映射
+func LoadFileKeyStore(path string) (*MemoryKeyStore, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var keys map[string]string
+	if err = yaml.Unmarshal(data, &keys); err != nil {
+		return nil, err
+	}
+	return NewMemoryKeyStore(keys), nil
+}
+
+// Lookup 查找 key
+func (s *MemoryKeyStore) Lookup(ctx context.Context, key string) (subject string, ok bool, err error) {
+	s.mu.RLock()
+	subject, ok = s.keys[key]
+	s.mu.RUnlock()
+	return
+}
+
+// Set 添加或更新 key
+func (s *MemoryKeyStore) Set(key, subject string) {
+	s.mu.Lock()
+	s.keys[key] = subject
+	s.mu.Unlock()
+}
+
+// Delete 删除 key
+func (s *MemoryKeyStore) Delete(key string) {
+	s.mu.Lock()
+	delete(s.keys, key)
+	s.mu.Unlock()
+}
+
+// APIKeyAuthenticator 校验 x-api-key metadata
+type APIKeyAuthenticator struct {
+	store       KeyStore
+	skipMethods []string
+}
+
+// NewAPIKeyAuthenticator 创建 API key 认证，skipMethods 为空时使用 DefaultAuthSkipMethods
+func NewAPIKeyAuthenticator(store KeyStore, skipMethods ...string) *APIKeyAuthenticator {
+	if len(skipMethods) == 0 {
+		skipMethods = DefaultAuthSkipMethods
+	}
+	return &APIKeyAuthenticator{store: store, skipMethods: skipMethods}
+}
+
+// Authenticate 校验 API key 并将主体放入上下文
+func (a *APIKeyAuthenticator) Authenticate(ctx context.Context) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	keys := md.Get(APIKeyHeader)
+	if len(keys) == 0 || keys[0] == "" {
+		return nil, status.Error(codes.Unauthenticated, "missing api key")
+	}
+	subject, ok, err := a.store.Lookup(ctx, keys[0])
+	if err != nil {
+		return nil, status.Errorf(codes.Unavailable, "lookup api key: %v", err)
+	}
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "invalid api key")
+	}
+	return ContextWithSubject(ctx, subject), nil
+}
+
+// UnaryServerInterceptor API key 认证一元拦截器
+func (a *APIKeyAuthenticator) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+		if matchAnyMethod(a.skipMethods, info.FullMethod) {
+			return handler(ctx, req)
+		}
+		if ctx, err = a.Authenticate(ctx); err != nil {
+			return
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor API key 认证流拦截器
+func (a *APIKeyAuthenticator) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if matchAnyMethod(a.skipMethods, info.FullMethod) {
+			return handler(srv, stream)
+		}
+		ctx, err := a.Authenticate(stream.Context())
+		if err != nil {
+			return err
+		}
+		wrapped := WrapServerStream(stream)
+		wrapped.WrappedContext = ctx
+		return handler(srv, wrapped)
+	}
+}
+
+// WithServerAPIKeyAuth 启用 API key 认证，skipMethods 为空时使用 DefaultAuthSkipMethods
+func WithServerAPIKeyAuth(store KeyStore, skipMethods ...string) ServerOption {
+	authenticator := NewAPIKeyAuthenticator(store, skipMethods...)
+	return func(o *ServerOptions) {
+		o.unaryInterceptors = append(o.unaryInterceptors, authenticator.UnaryServerInterceptor())
+		o.streamInterceptors = append(o.streamInterceptors, authenticator.StreamServerInterceptor())
+	}
+}
+
+// WithClientAPIKey 每次调用附加 x-api-key
+func WithClientAPIKey(key string) ClientOption {
+	return func(o *ClientOptions) {
+		o.unaryInterceptors = append(o.unaryInterceptors, func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+			return invoker(metadata.AppendToOutgoingContext(ctx, APIKeyHeader, key), method, req, reply, cc, opts...)
+		})
+		o.streamInterceptors = append(o.streamInterceptors, func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+			return streamer(metadata.AppendToOutgoingContext(ctx, APIKeyHeader, key), desc, cc, method, opts...)
+		})
+	}
+}
@@ -0,0 +1,229 @@
+package ngrpc
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// SignatureKeyIDHeader 签名密钥ID
+	SignatureKeyIDHeader = "x-signature-key-id"
+	// SignatureTimestampHeader 签名时间戳（Unix秒）
+	SignatureTimestampHeader = "x-signature-timestamp"
+	// SignatureNonceHeader 签名随机数
+	SignatureNonceHeader = "x-signature-nonce"
+	// SignatureHeader 签名
+	SignatureHeader = "x-signature"
+)
+
+// SecretStore HMAC 密钥存储
+type SecretStore interface {
+	Secret(ctx context.Context, keyID string) (secret []byte, ok bool, err error)
+}
+
+// SecretStoreFunc 函数形式的 SecretStore
+type SecretStoreFunc func(ctx context.Context, keyID string) (secret []byte, ok bool, err error)
+
+// Secret 查找密钥
+func (f SecretStoreFunc) Secret(ctx context.Context, keyID string) ([]byte, bool, error) {
+	return f(ctx, keyID)
+}
+
+// MemorySecretStore 内存 SecretStore
+type MemorySecretStore map[string][]byte
+
+// Secret 查找密钥
+func (s MemorySecretStore) Secret(ctx context.Context, keyID string) (secret []byte, ok bool, err error) {
+	secret, ok = s[keyID]
+	return
+}
+
+// signRequest 签名内容为 method、timestamp、nonce，以换行分隔；
+// 请求体不参与签名：proto 编码不唯一，重新编码无法还原客户端发送的字节，流式调用也没有单一的请求体，
+// 请求体的完整性由 TLS 保证
+func signRequest(secret []byte, method, timestamp, nonce string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(method + "\n" + timestamp + "\n" + nonce))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// HMACSigner 客户端请求签名
+type HMACSigner struct {
+	keyID  string
+	secret []byte
+}
+
+// NewHMACSigner 创建请求签名
+func NewHMACSigner(keyID string, secret []byte) *HMACSigner {
+	return &HMACSigner{keyID: keyID, secret: secret}
+}
+
+func (s *HMACSigner) sign(ctx context.Context, method string) context.Context {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	nonce := newRequestID()
+	return metadata.AppendToOutgoingContext(ctx,
+		SignatureKeyIDHeader, s.keyID,
+		SignatureTimestampHeader, timestamp,
+		SignatureNonceHeader, nonce,
+		SignatureHeader, signRequest(s.secret, method, timestamp, nonce),
+	)
+}
+
+// UnaryClientInterceptor 签名一元拦截器
+func (s *HMACSigner) UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		return invoker(s.sign(ctx, method), method, req, reply, cc, opts...)
+	}
+}
+
+// StreamClientInterceptor 签名流拦截器
+func (s *HMACSigner) StreamClientInterceptor() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		return streamer(s.sign(ctx, method), desc, cc, method, opts...)
+	}
+}
+
+// WithClientHMACSigner 对每次调用的方法、时间戳和随机数签名，不包括请求体
+func WithClientHMACSigner(keyID string, secret []byte) ClientOption {
+	signer := NewHMACSigner(keyID, secret)
+	return func(o *ClientOptions) {
+		o.unaryInterceptors = append(o.unaryInterceptors, signer.UnaryClientInterceptor())
+		o.streamInterceptors = append(o.streamInterceptors, signer.StreamClientInterceptor())
+	}
+}
+
+// HMACVerifier 服务端签名校验，拒绝时间偏差超过 maxSkew 或重复 nonce 的请求
+type HMACVerifier struct {
+	store       SecretStore
+	maxSkew     time.Duration
+	skipMethods []string
+
+	mu     sync.Mutex
+	nonces map[string]time.Time
+	sweep  time.Time
+}
+
+// NewHMACVerifier 创建签名校验，maxSkew 为0时使用5分钟，skipMethods 为空时使用 DefaultAuthSkipMethods
+func NewHMACVerifier(store SecretStore, maxSkew time.Duration, skipMethods ...string) *HMACVerifier {
+	if maxSkew <= 0 {
+		maxSkew = 5 * time.Minute
+	}
+	if len(skipMethods) == 0 {
+		skipMethods = DefaultAuthSkipMethods
+	}
+	return &HMACVerifier{
+		store:       store,
+		maxSkew:     maxSkew,
+		skipMethods: skipMethods,
+		nonces:      make(map[string]time.Time),
+	}
+}
+
+func firstMetadata(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// Verify 校验签名，通过后将密钥ID作为主体放入上下文
+func (v *HMACVerifier) Verify(ctx context.Context, method string) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	keyID := firstMetadata(md, SignatureKeyIDHeader)
+	timestamp := firstMetadata(md, SignatureTimestampHeader)
+	nonce := firstMetadata(md, SignatureNonceHeader)
+	signature := firstMetadata(md, SignatureHeader)
+	if keyID == "" || timestamp == "" || nonce == "" || signature == "" {
+		return nil, status.Error(codes.Unauthenticated, "missing request signature")
+	}
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, "invalid signature timestamp")
+	}
+	signedAt := time.Unix(unix, 0)
+	if skew := time.Since(signedAt); skew > v.maxSkew || skew < -v.maxSkew {
+		return nil, status.Error(codes.Unauthenticated, "signature timestamp out of range")
+	}
+	secret, ok, err := v.store.Secret(ctx, keyID)
+	if err != nil {
+		return nil, status.Errorf(codes.Unavailable, "lookup signature key: %v", err)
+	}
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "unknown signature key")
+	}
+	expected := signRequest(secret, method, timestamp, nonce)
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return nil, status.Error(codes.Unauthenticated, "invalid request signature")
+	}
+	if !v.useNonce(keyID+":"+nonce, signedAt.Add(v.maxSkew)) {
+		return nil, status.Error(codes.Unauthenticated, "replayed request")
+	}
+	return ContextWithSubject(ctx, keyID), nil
+}
+
+// useNonce 记录 nonce 直到签名过期，已存在时返回 false
+func (v *HMACVerifier) useNonce(nonce string, expires time.Time) bool {
+	now := time.Now()
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if now.Sub(v.sweep) > v.maxSkew {
+		for n, exp := range v.nonces {
+			if now.After(exp) {
+				delete(v.nonces, n)
+			}
+		}
+		v.sweep = now
+	}
+	if _, ok := v.nonces[nonce]; ok {
+		return false
+	}
+	v.nonces[nonce] = expires
+	return true
+}
+
+// UnaryServerInterceptor 签名校验一元拦截器
+func (v *HMACVerifier) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+		if matchAnyMethod(v.skipMethods, info.FullMethod) {
+			return handler(ctx, req)
+		}
+		if ctx, err = v.Verify(ctx, info.FullMethod); err != nil {
+			return
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor 签名校验流拦截器
+func (v *HMACVerifier) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if matchAnyMethod(v.skipMethods, info.FullMethod) {
+			return handler(srv, stream)
+		}
+		ctx, err := v.Verify(stream.Context(), info.FullMethod)
+		if err != nil {
+			return err
+		}
+		wrapped := WrapServerStream(stream)
+		wrapped.WrappedContext = ctx
+		return handler(srv, wrapped)
+	}
+}
+
+// WithServerHMACAuth 启用请求签名校验
+func WithServerHMACAuth(verifier *HMACVerifier) ServerOption {
+	return func(o *ServerOptions) {
+		o.unaryInterceptors = append(o.unaryInterceptors, verifier.UnaryServerInterceptor())
+		o.streamInterceptors = append(o.streamInterceptors, verifier.StreamServerInterceptor())
+	}
+}
@@ -0,0 +1,212 @@
+package ngrpc
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/oauth2"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// DefaultAuthSkipMethods 默认跳过认证的方法：健康检查和反射
+var DefaultAuthSkipMethods = []string{
+	"/grpc.health.v1.Health/*",
+	"/grpc.reflection.v1.ServerReflection/*",
+	"/grpc.reflection.v1alpha.ServerReflection/*",
+}
+
+type claimsKey struct{}
+
+// ClaimsFromContext 获取认证通过的JWT声明
+func ClaimsFromContext(ctx context.Context) (claims jwt.MapClaims, ok bool) {
+	claims, ok = ctx.Value(claimsKey{}).(jwt.MapClaims)
+	return
+}
+
+// JWTOptions JWT认证可选参数
+type JWTOptions struct {
+	Issuer         string
+	Audience       string
+	Leeway         time.Duration
+	SigningMethods []string
+	SkipMethods    []string
+}
+
+// JWTOption 为JWT认证可选参数赋值的函数
+type JWTOption func(*JWTOptions)
+
+// WithJWTIssuer 校验 iss
+func WithJWTIssuer(issuer string) JWTOption {
+	return func(o *JWTOptions) {
+		o.Issuer = issuer
+	}
+}
+
+// WithJWTAudience 校验 aud
+func WithJWTAudience(audience string) JWTOption {
+	return func(o *JWTOptions) {
+		o.Audience = audience
+	}
+}
+
+// WithJWTLeeway 校验 exp/nbf/iat 时允许的时钟偏差
+func WithJWTLeeway(leeway time.Duration) JWTOption {
+	return func(o *JWTOptions) {
+		o.Leeway = leeway
+	}
+}
+
+// WithJWTSigningMethods 允许的签名算法，如 RS256、ES256
+func WithJWTSigningMethods(methods ...string) JWTOption {
+	return func(o *JWTOptions) {
+		o.SigningMethods = methods
+	}
+}
+
+// WithJWTSkipMethods 跳过认证的方法，支持 /pkg.Service/* 形式，会覆盖 DefaultAuthSkipMethods
+func WithJWTSkipMethods(methods ...string) JWTOption {
+	return func(o *JWTOptions) {
+		o.SkipMethods = methods
+	}
+}
+
+// JWTAuthenticator 校验 authorization metadata 中的 bearer token
+type JWTAuthenticator struct {
+	keyfunc jwt.Keyfunc
+	opts    JWTOptions
+	parser  *jwt.Parser
+}
+
+// NewJWTAuthenticator 创建JWT认证，keyfunc 可使用 JWKS.Keyfunc
+func NewJWTAuthenticator(keyfunc jwt.Keyfunc, opts ...JWTOption) *JWTAuthenticator {
+	o := JWTOptions{
+		SigningMethods: []string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512", "PS256", "PS384", "PS512", "EdDSA"},
+		SkipMethods:    DefaultAuthSkipMethods,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	parserOptions := []jwt.ParserOption{
+		jwt.WithValidMethods(o.SigningMethods),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(o.Leeway),
+	}
+	if o.Issuer != "" {
+		parserOptions = append(parserOptions, jwt.WithIssuer(o.Issuer))
+	}
+	if o.Audience != "" {
+		parserOptions = append(parserOptions, jwt.WithAudience(o.Audience))
+	}
+	return &JWTAuthenticator{
+		keyfunc: keyfunc,
+		opts:    o,
+		parser:  jwt.NewParser(parserOptions...),
+	}
+}
+
+// BearerToken 从 authorization metadata 获取 bearer token
+func BearerToken(ctx context.Context) (token string, ok bool) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, value := range md.Get("authorization") {
+		if len(value) > 7 && strings.EqualFold(value[:7], "bearer ") {
+			return strings.TrimSpace(value[7:]), true
+		}
+	}
+	return
+}
+
+// Authenticate 校验token并将声明放入上下文
+func (a *JWTAuthenticator) Authenticate(ctx context.Context) (context.Context, error) {
+	raw, ok := BearerToken(ctx)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "missing bearer token")
+	}
+	claims := jwt.MapClaims{}
+	if _, err := a.parser.ParseWithClaims(raw, claims, a.keyfunc); err != nil {
+		return nil, status.Errorf(codes.Unauthenticated, "invalid token: %v", err)
+	}
+	ctx = context.WithValue(ctx, claimsKey{}, claims)
+	if subject, err := claims.GetSubject(); err == nil && subject != "" {
+		ctx = ContextWithSubject(ctx, subject)
+	}
+	return ctx, nil
+}
+
+// UnaryServerInterceptor JWT认证一元拦截器
+func (a *JWTAuthenticator) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+		if matchAnyMethod(a.opts.SkipMethods, info.FullMethod) {
+			return handler(ctx, req)
+		}
+		if ctx, err = a.Authenticate(ctx); err != nil {
+			return
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor JWT认证流拦截器
+func (a *JWTAuthenticator) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if matchAnyMethod(a.opts.SkipMethods, info.FullMethod) {
+			return handler(srv, stream)
+		}
+		ctx, err := a.Authenticate(stream.Context())
+		if err != nil {
+			return err
+		}
+		wrapped := WrapServerStream(stream)
+		wrapped.WrappedContext = ctx
+		return handler(srv, wrapped)
+	}
+}
+
+// WithServerJWTAuth 启用JWT认证
+func WithServerJWTAuth(authenticator *JWTAuthenticator) ServerOption {
+	return func(o *ServerOptions) {
+		o.unaryInterceptors = append(o.unaryInterceptors, authenticator.UnaryServerInterceptor())
+		o.streamInterceptors = append(o.streamInterceptors, authenticator.StreamServerInterceptor())
+	}
+}
+
+// tokenCredentials 从 oauth2.TokenSource 获取token的 PerRPCCredentials
+type tokenCredentials struct {
+	source     oauth2.TokenSource
+	requireTLS bool
+}
+
+// NewTokenCredentials 创建基于 oauth2.TokenSource 的 PerRPCCredentials，token 过期前自动刷新；
+// requireTLS 为 false 时允许在非TLS连接上发送token
+func NewTokenCredentials(source oauth2.TokenSource, requireTLS bool) credentials.PerRPCCredentials {
+	return &tokenCredentials{
+		source:     oauth2.ReuseTokenSource(nil, source),
+		requireTLS: requireTLS,
+	}
+}
+
+func (c *tokenCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	token, err := c.source.Token()
+	if err != nil {
+		return nil, status.Errorf(codes.Unauthenticated, "get token: %v", err)
+	}
+	return map[string]string{
+		"authorization": token.Type() + " " + token.AccessToken,
+	}, nil
+}
+
+func (c *tokenCredentials) RequireTransportSecurity() bool {
+	return c.requireTLS
+}
+
+// WithClientPerRPCCredentials 每次调用附加认证信息
+func WithClientPerRPCCredentials(creds credentials.PerRPCCredentials) ClientOption {
+	return func(o *ClientOptions) {
+		o.PerRPCCredentials = creds
+	}
+}
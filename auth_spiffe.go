@@ -0,0 +1,212 @@
+package ngrpc
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// PeerIdentity mTLS 对端身份
+type PeerIdentity struct {
+	// SPIFFEID 证书 URI SAN 中的 spiffe://trust-domain/path
+	SPIFFEID *url.URL
+	// CommonName 证书 CN
+	CommonName  string
+	Certificate *x509.Certificate
+}
+
+// TrustDomain SPIFFE 信任域
+func (p *PeerIdentity) TrustDomain() string {
+	if p.SPIFFEID == nil {
+		return ""
+	}
+	return p.SPIFFEID.Host
+}
+
+// String SPIFFE ID，没有时返回 CN
+func (p *PeerIdentity) String() string {
+	if p.SPIFFEID != nil {
+		return p.SPIFFEID.String()
+	}
+	return p.CommonName
+}
+
+type peerIdentityKey struct{}
+
+// PeerIdentityFromPeer 从已校验的对端证书提取身份
+func PeerIdentityFromPeer(ctx context.Context) (*PeerIdentity, error) {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "no peer")
+	}
+	tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "peer is not using TLS")
+	}
+	if len(tlsInfo.State.VerifiedChains) == 0 || len(tlsInfo.State.VerifiedChains[0]) == 0 {
+		return nil, status.Error(codes.Unauthenticated, "peer certificate is not verified")
+	}
+	cert := tlsInfo.State.VerifiedChains[0][0]
+	identity := &PeerIdentity{
+		CommonName:  cert.Subject.CommonName,
+		Certificate: cert,
+	}
+	for _, uri := range cert.URIs {
+		if uri.Scheme != "spiffe" {
+			continue
+		}
+		if identity.SPIFFEID != nil {
+			return nil, status.Error(codes.Unauthenticated, "peer certificate has multiple SPIFFE IDs")
+		}
+		identity.SPIFFEID = uri
+	}
+	return identity, nil
+}
+
+// ContextWithPeerIdentity 将对端身份放入上下文
+func ContextWithPeerIdentity(ctx context.Context, identity *PeerIdentity) context.Context {
+	return ContextWithSubject(context.WithValue(ctx, peerIdentityKey{}, identity), identity.String())
+}
+
+// PeerIdentityFromContext 获取对端身份
+func PeerIdentityFromContext(ctx context.Context) (identity *PeerIdentity, ok bool) {
+	identity, ok = ctx.Value(peerIdentityKey{}).(*PeerIdentity)
+	return
+}
+
+// SPIFFERule 方法允许的 SPIFFE ID 或信任域，Methods 支持 /pkg.Service/* 形式
+type SPIFFERule struct {
+	Methods      []string
+	IDs          []string
+	TrustDomains []string
+}
+
+func (r SPIFFERule) allow(identity *PeerIdentity) bool {
+	if identity.SPIFFEID == nil {
+		return false
+	}
+	id := identity.SPIFFEID.String()
+	for _, allowed := range r.IDs {
+		if allowed == id {
+			return true
+		}
+	}
+	for _, domain := range r.TrustDomains {
+		if strings.EqualFold(strings.TrimPrefix(domain, "spiffe://"), identity.TrustDomain()) {
+			return true
+		}
+	}
+	return false
+}
+
+// SPIFFEAuthorizer 按 SPIFFE ID 或信任域授权方法，未匹配规则的方法拒绝
+type SPIFFEAuthorizer struct {
+	rules       []SPIFFERule
+	skipMethods []string
+}
+
+// NewSPIFFEAuthorizer 创建 SPIFFE 授权，按顺序匹配第一条规则
+func NewSPIFFEAuthorizer(rules ...SPIFFERule) *SPIFFEAuthorizer {
+	return &SPIFFEAuthorizer{rules: rules}
+}
+
+// SkipMethods 设置跳过授权的方法
+func (a *SPIFFEAuthorizer) SkipMethods(methods ...string) *SPIFFEAuthorizer {
+	a.skipMethods = methods
+	return a
+}
+
+// Authorize 提取对端身份并校验
+func (a *SPIFFEAuthorizer) Authorize(ctx context.Context, fullMethod string) (context.Context, error) {
+	identity, err := PeerIdentityFromPeer(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ctx = ContextWithPeerIdentity(ctx, identity)
+	for _, rule := range a.rules {
+		if !matchAnyMethod(rule.Methods, fullMethod) {
+			continue
+		}
+		if rule.allow(identity) {
+			return ctx, nil
+		}
+		break
+	}
+	return nil, status.Error(codes.PermissionDenied, fmt.Sprintf("peer %s is not allowed to call %s", identity, fullMethod))
+}
+
+// UnaryServerInterceptor SPIFFE 授权一元拦截器
+func (a *SPIFFEAuthorizer) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+		if matchAnyMethod(a.skipMethods, info.FullMethod) {
+			return handler(ctx, req)
+		}
+		if ctx, err = a.Authorize(ctx, info.FullMethod); err != nil {
+			return
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor SPIFFE 授权流拦截器
+func (a *SPIFFEAuthorizer) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if matchAnyMethod(a.skipMethods, info.FullMethod) {
+			return handler(srv, stream)
+		}
+		ctx, err := a.Authorize(stream.Context(), info.FullMethod)
+		if err != nil {
+			return err
+		}
+		wrapped := WrapServerStream(stream)
+		wrapped.WrappedContext = ctx
+		return handler(srv, wrapped)
+	}
+}
+
+// PeerIdentityContextHandler 提取 mTLS 对端身份放入上下文，提取失败时不做处理
+func PeerIdentityContextHandler(ctx context.Context) context.Context {
+	identity, err := PeerIdentityFromPeer(ctx)
+	if err != nil {
+		return ctx
+	}
+	return ContextWithPeerIdentity(ctx, identity)
+}
+
+// WithServerPeerIdentity 将 mTLS 对端身份放入上下文
+func WithServerPeerIdentity() ServerOption {
+	return func(o *ServerOptions) {
+		o.unaryInterceptors = append(o.unaryInterceptors, UnaryServerInterceptor(PeerIdentityContextHandler))
+		o.streamInterceptors = append(o.streamInterceptors, StreamServerInterceptor(PeerIdentityContextHandler))
+	}
+}
+
+// WithServerSPIFFEAuthorizer 启用 SPIFFE 授权
+func WithServerSPIFFEAuthorizer(authorizer *SPIFFEAuthorizer) ServerOption {
+	return func(o *ServerOptions) {
+		o.unaryInterceptors = append(o.unaryInterceptors, authorizer.UnaryServerInterceptor())
+		o.streamInterceptors = append(o.streamInterceptors, authorizer.StreamServerInterceptor())
+	}
+}
+
+// WithServerCredentials 设置传输层凭证，如 mTLS
+func WithServerCredentials(creds credentials.TransportCredentials) ServerOption {
+	return func(o *ServerOptions) {
+		o.Credentials = creds
+	}
+}
+
+// WithClientCredentials 设置传输层凭证，默认不加密
+func WithClientCredentials(creds credentials.TransportCredentials) ClientOption {
+	return func(o *ClientOptions) {
+		o.Credentials = creds
+	}
+}
@@ -0,0 +1,192 @@
+package ngrpc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// subjectRecorder 记录处理函数看到的认证主体
+type subjectRecorder struct {
+	mu       sync.Mutex
+	subjects []string
+}
+
+func (r *subjectRecorder) add(ctx context.Context) {
+	subject, _ := SubjectFromContext(ctx)
+	r.mu.Lock()
+	r.subjects = append(r.subjects, subject)
+	r.mu.Unlock()
+}
+
+func (r *subjectRecorder) list() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.subjects...)
+}
+
+func (r *subjectRecorder) options() []ServerOption {
+	return []ServerOption{
+		WithServerUnaryServerInterceptors(func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			r.add(ctx)
+			return handler(ctx, req)
+		}),
+		WithServerStreamServerInterceptors(func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			r.add(stream.Context())
+			return handler(srv, stream)
+		}),
+	}
+}
+
+// callEcho 分别发起一元调用和流式调用
+func callEcho(conn *grpc.ClientConn) (unaryErr, streamErr error) {
+	ctx := context.Background()
+	unaryErr = conn.Invoke(ctx, echoUnary, wrapperspb.String("hi"), new(wrapperspb.StringValue))
+	stream, err := conn.NewStream(ctx, &echoServiceDesc.Streams[0], echoServerStream)
+	if err == nil {
+		if err = stream.SendMsg(wrapperspb.String("hi")); err == nil {
+			err = stream.CloseSend()
+		}
+	}
+	for err == nil {
+		err = stream.RecvMsg(new(wrapperspb.StringValue))
+	}
+	if !errors.Is(err, io.EOF) {
+		streamErr = err
+	}
+	return
+}
+
+func TestAPIKeyAuth(t *testing.T) {
+	recorder := new(subjectRecorder)
+	store := NewMemoryKeyStore(map[string]string{"key-a": "svc-a"})
+	server := startEchoServer(t, append(recorder.options(), WithServerAPIKeyAuth(store))...)
+
+	conn := dialEcho(t, server, grpc.WithChainUnaryInterceptor(withAPIKey("key-a")), grpc.WithChainStreamInterceptor(withAPIKeyStream("key-a")))
+	if unaryErr, streamErr := callEcho(conn); unaryErr != nil || streamErr != nil {
+		t.Fatalf("valid key: unary %v, stream %v", unaryErr, streamErr)
+	}
+	if got := recorder.list(); len(got) != 2 || got[0] != "svc-a" || got[1] != "svc-a" {
+		t.Fatalf("subjects = %q, want svc-a twice", got)
+	}
+	for name, conn := range map[string]*grpc.ClientConn{
+		"missing": dialEcho(t, server),
+		"unknown": dialEcho(t, server, grpc.WithChainUnaryInterceptor(withAPIKey("key-b")), grpc.WithChainStreamInterceptor(withAPIKeyStream("key-b"))),
+	} {
+		unaryErr, streamErr := callEcho(conn)
+		if status.Code(unaryErr) != codes.Unauthenticated || status.Code(streamErr) != codes.Unauthenticated {
+			t.Fatalf("%s key: unary %v, stream %v, want Unauthenticated", name, unaryErr, streamErr)
+		}
+	}
+	// 删除后立即失效
+	store.Delete("key-a")
+	if unaryErr, _ := callEcho(conn); status.Code(unaryErr) != codes.Unauthenticated {
+		t.Fatalf("deleted key: %v, want Unauthenticated", unaryErr)
+	}
+}
+
+// withAPIKey 与 WithClientAPIKey 相同的拦截器，用于直接拨号的连接
+func withAPIKey(key string) grpc.UnaryClientInterceptor {
+	return NewClientOptions(WithClientAPIKey(key)).unaryInterceptors[0]
+}
+
+func withAPIKeyStream(key string) grpc.StreamClientInterceptor {
+	return NewClientOptions(WithClientAPIKey(key)).streamInterceptors[0]
+}
+
+func TestHMACAuth(t *testing.T) {
+	recorder := new(subjectRecorder)
+	secret := []byte("secret")
+	verifier := NewHMACVerifier(MemorySecretStore{"svc-a": secret}, time.Minute)
+	server := startEchoServer(t, append(recorder.options(), WithServerHMACAuth(verifier))...)
+
+	signer := NewHMACSigner("svc-a", secret)
+	conn := dialEcho(t, server, grpc.WithChainUnaryInterceptor(signer.UnaryClientInterceptor()), grpc.WithChainStreamInterceptor(signer.StreamClientInterceptor()))
+	if unaryErr, streamErr := callEcho(conn); unaryErr != nil || streamErr != nil {
+		t.Fatalf("signed: unary %v, stream %v", unaryErr, streamErr)
+	}
+	if got := recorder.list(); len(got) != 2 || got[0] != "svc-a" || got[1] != "svc-a" {
+		t.Fatalf("subjects = %q, want svc-a twice", got)
+	}
+	wrong := NewHMACSigner("svc-a", []byte("wrong"))
+	for name, conn := range map[string]*grpc.ClientConn{
+		"unsigned":     dialEcho(t, server),
+		"wrong secret": dialEcho(t, server, grpc.WithChainUnaryInterceptor(wrong.UnaryClientInterceptor()), grpc.WithChainStreamInterceptor(wrong.StreamClientInterceptor())),
+	} {
+		unaryErr, streamErr := callEcho(conn)
+		if status.Code(unaryErr) != codes.Unauthenticated || status.Code(streamErr) != codes.Unauthenticated {
+			t.Fatalf("%s: unary %v, stream %v, want Unauthenticated", name, unaryErr, streamErr)
+		}
+	}
+}
+
+// signedContext 服务端收到的带签名 metadata 的上下文
+func signedContext(keyID string, secret []byte, method string, signedAt time.Time, nonce string) context.Context {
+	timestamp := strconv.FormatInt(signedAt.Unix(), 10)
+	return metadata.NewIncomingContext(context.Background(), metadata.Pairs(
+		SignatureKeyIDHeader, keyID,
+		SignatureTimestampHeader, timestamp,
+		SignatureNonceHeader, nonce,
+		SignatureHeader, signRequest(secret, method, timestamp, nonce),
+	))
+}
+
+func TestHMACVerify(t *testing.T) {
+	const method = "/pkg.Service/Call"
+	secret := []byte("secret")
+	now := time.Now()
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		method  string
+		want    codes.Code
+		subject string
+	}{
+		{name: "valid", ctx: signedContext("svc-a", secret, method, now, "n1"), subject: "svc-a"},
+		{name: "within skew", ctx: signedContext("svc-a", secret, method, now.Add(-4*time.Minute), "n2"), subject: "svc-a"},
+		{name: "future within skew", ctx: signedContext("svc-a", secret, method, now.Add(4*time.Minute), "n3"), subject: "svc-a"},
+		{name: "expired", ctx: signedContext("svc-a", secret, method, now.Add(-6*time.Minute), "n4"), want: codes.Unauthenticated},
+		{name: "future", ctx: signedContext("svc-a", secret, method, now.Add(6*time.Minute), "n5"), want: codes.Unauthenticated},
+		{name: "other method", ctx: signedContext("svc-a", secret, "/pkg.Service/Other", now, "n6"), want: codes.Unauthenticated},
+		{name: "unknown key", ctx: signedContext("svc-b", secret, method, now, "n7"), want: codes.Unauthenticated},
+		{name: "missing", ctx: context.Background(), want: codes.Unauthenticated},
+		{name: "replayed", ctx: signedContext("svc-a", secret, method, now, "n1"), want: codes.Unauthenticated},
+	}
+	verifier := NewHMACVerifier(MemorySecretStore{"svc-a": secret}, 5*time.Minute)
+	for _, tt := range tests {
+		ctx, err := verifier.Verify(tt.ctx, method)
+		if status.Code(err) != tt.want {
+			t.Fatalf("%s: Verify() = %v, want %s", tt.name, err, tt.want)
+		}
+		if err != nil {
+			continue
+		}
+		if subject, _ := SubjectFromContext(ctx); subject != tt.subject {
+			t.Fatalf("%s: subject %q, want %q", tt.name, subject, tt.subject)
+		}
+	}
+	// 其他密钥可以使用相同的 nonce
+	verifier = NewHMACVerifier(MemorySecretStore{"svc-a": secret, "svc-b": secret}, 5*time.Minute)
+	for _, keyID := range []string{"svc-a", "svc-b"} {
+		if _, err := verifier.Verify(signedContext(keyID, secret, method, now, "same"), method); err != nil {
+			t.Fatalf("%s: %v", keyID, err)
+		}
+	}
+	// 查找密钥失败时返回 Unavailable
+	failing := NewHMACVerifier(SecretStoreFunc(func(ctx context.Context, keyID string) ([]byte, bool, error) {
+		return nil, false, errors.New("store down")
+	}), 0)
+	if _, err := failing.Verify(signedContext("svc-a", secret, method, now, "n8"), method); status.Code(err) != codes.Unavailable {
+		t.Fatalf("failing store: %v, want Unavailable", err)
+	}
+}
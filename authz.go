@@ -0,0 +1,304 @@
+package ngrpc
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// AuthzDefaultDeny 未匹配规则的请求拒绝
+	AuthzDefaultDeny = "deny"
+	// AuthzDefaultAllow 未匹配规则的请求放行
+	AuthzDefaultAllow = "allow"
+	// AuthzModeEnforce 拒绝不满足策略的请求
+	AuthzModeEnforce = "enforce"
+	// AuthzModeAudit 仅记录不满足策略的请求，不拒绝
+	AuthzModeAudit = "audit"
+)
+
+// AuthzRule 授权规则，Methods 支持 /pkg.Service/* 形式，
+// 主体需拥有 Roles 中任一角色且拥有全部 Scopes，Public 为 true 时不做校验；Roles、Scopes 和 Public 至少设置一项
+type AuthzRule struct {
+	Methods []string `yaml:"methods" json:"methods"`
+	Roles   []string `yaml:"roles" json:"roles"`
+	Scopes  []string `yaml:"scopes" json:"scopes"`
+	Public  bool     `yaml:"public" json:"public"`
+}
+
+// AuthzPolicy 授权策略，按顺序匹配第一条规则
+type AuthzPolicy struct {
+	Default string      `yaml:"default" json:"default"`
+	Mode    string      `yaml:"mode" json:"mode"`
+	Rules   []AuthzRule `yaml:"rules" json:"rules"`
+}
+
+// LoadAuthzPolicy 从YAML或JSON文件加载授权策略
+func LoadAuthzPolicy(path string) (*AuthzPolicy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseAuthzPolicy(data)
+}
+
+// ParseAuthzPolicy 解析YAML或JSON格式的授权策略
+func ParseAuthzPolicy(data []byte) (*AuthzPolicy, error) {
+	policy := new(AuthzPolicy)
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	// 拼错的字段（如 role）会让受保护的方法变为公开，按错误处理
+	dec.KnownFields(true)
+	if err := dec.Decode(policy); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("authz policy: %w", err)
+	}
+	if err := policy.validate(); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+func (p *AuthzPolicy) validate() error {
+	switch p.Default {
+	case "":
+		p.Default = AuthzDefaultDeny
+	case AuthzDefaultDeny, AuthzDefaultAllow:
+	default:
+		return fmt.Errorf("authz policy: default must be %q or %q, got %q", AuthzDefaultDeny, AuthzDefaultAllow, p.Default)
+	}
+	switch p.Mode {
+	case "":
+		p.Mode = AuthzModeEnforce
+	case AuthzModeEnforce, AuthzModeAudit:
+	default:
+		return fmt.Errorf("authz policy: mode must be %q or %q, got %q", AuthzModeEnforce, AuthzModeAudit, p.Mode)
+	}
+	for i, rule := range p.Rules {
+		if len(rule.Methods) == 0 {
+			return fmt.Errorf("authz policy: rules[%d]: methods is required", i)
+		}
+		for _, method := range rule.Methods {
+			if method != "*" && !strings.HasPrefix(method, "/") {
+				return fmt.Errorf("authz policy: rules[%d]: method %q must be a full method name like /pkg.Service/Method", i, method)
+			}
+		}
+		if len(rule.Roles) == 0 && len(rule.Scopes) == 0 && !rule.Public {
+			return fmt.Errorf("authz policy: rules[%d]: one of roles, scopes or public: true is required", i)
+		}
+	}
+	return nil
+}
+
+// Check 判断主体是否可以调用方法，不允许时返回原因
+func (p *AuthzPolicy) Check(fullMethod string, roles, scopes []string) (allowed bool, reason string) {
+	for _, rule := range p.Rules {
+		if !matchAnyMethod(rule.Methods, fullMethod) {
+			continue
+		}
+		if rule.Public {
+			return true, ""
+		}
+		if len(rule.Roles) > 0 && !containsAny(roles, rule.Roles) {
+			return false, fmt.Sprintf("requires one of roles %v", rule.Roles)
+		}
+		for _, scope := range rule.Scopes {
+			if !containsAny(scopes, []string{scope}) {
+				return false, fmt.Sprintf("requires scope %q", scope)
+			}
+		}
+		return true, ""
+	}
+	if p.Default == AuthzDefaultAllow {
+		return true, ""
+	}
+	return false, "no matching rule"
+}
+
+func containsAny(have, want []string) bool {
+	for _, w := range want {
+		for _, h := range have {
+			if h == w {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// AuthzPrincipalFunc 从上下文获取调用方的角色和权限范围
+type AuthzPrincipalFunc func(ctx context.Context) (roles, scopes []string)
+
+// ClaimsPrincipal 从JWT声明获取角色（roles）和权限范围（scope 或 scp）
+func ClaimsPrincipal(ctx context.Context) (roles, scopes []string) {
+	claims, ok := ClaimsFromContext(ctx)
+	if !ok {
+		return
+	}
+	roles = claimStrings(claims["roles"])
+	if scope, ok := claims["scope"].(string); ok {
+		scopes = strings.Fields(scope)
+	} else {
+		scopes = claimStrings(claims["scp"])
+	}
+	return
+}
+
+func claimStrings(v interface{}) (values []string) {
+	switch x := v.(type) {
+	case string:
+		values = strings.Fields(x)
+	case []interface{}:
+		for _, item := range x {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+	case []string:
+		values = x
+	}
+	return
+}
+
+// Authorizer 方法级授权，策略可在运行时替换
+type Authorizer struct {
+	policy    atomic.Pointer[AuthzPolicy]
+	principal AuthzPrincipalFunc
+	log       atomic.Pointer[Logger]
+}
+
+// NewAuthorizer 创建授权，principal 为 nil 时使用 ClaimsPrincipal
+func NewAuthorizer(policy *AuthzPolicy, principal AuthzPrincipalFunc) *Authorizer {
+	if principal == nil {
+		principal = ClaimsPrincipal
+	}
+	a := &Authorizer{
+		principal: principal,
+	}
+	a.SetLogger(NewStdLogger(LogLevelDebug, nil, false))
+	a.SetPolicy(policy)
+	return a
+}
+
+// SetPolicy 替换策略
+func (a *Authorizer) SetPolicy(policy *AuthzPolicy) {
+	a.policy.Store(policy)
+}
+
+// SetLogger 设置日志
+func (a *Authorizer) SetLogger(log Logger) {
+	a.log.Store(&log)
+}
+
+func (a *Authorizer) logger() Logger {
+	return *a.log.Load()
+}
+
+// Policy 当前策略
+func (a *Authorizer) Policy() *AuthzPolicy {
+	return a.policy.Load()
+}
+
+// defaultWatchInterval 检查文件修改的默认间隔
+const defaultWatchInterval = 5 * time.Second
+
+// WatchFile 定期检查策略文件，修改后重新加载，加载失败时保留原策略；ctx 结束后停止，
+// interval 小于等于0时为5秒
+func (a *Authorizer) WatchFile(ctx context.Context, path string, interval time.Duration) {
+	if interval <= 0 {
+		interval = defaultWatchInterval
+	}
+	var modTime time.Time
+	if fi, err := os.Stat(path); err == nil {
+		modTime = fi.ModTime()
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			fi, err := os.Stat(path)
+			if err != nil {
+				a.logger().Errorf(ctx, "authz policy %s: %v", path, err)
+				continue
+			}
+			if fi.ModTime().Equal(modTime) {
+				continue
+			}
+			modTime = fi.ModTime()
+			policy, err := LoadAuthzPolicy(path)
+			if err != nil {
+				a.logger().Errorf(ctx, "authz policy %s reload failed, keeping previous policy: %v", path, err)
+				continue
+			}
+			a.SetPolicy(policy)
+			a.logger().Infof(ctx, "authz policy %s reloaded", path)
+		}
+	}()
+}
+
+// Authorize 校验上下文中的主体是否可以调用方法，DefaultAuthSkipMethods 中的健康检查和反射始终允许
+func (a *Authorizer) Authorize(ctx context.Context, fullMethod string) error {
+	if matchAnyMethod(DefaultAuthSkipMethods, fullMethod) {
+		return nil
+	}
+	policy := a.Policy()
+	if policy == nil {
+		return status.Error(codes.PermissionDenied, "permission denied")
+	}
+	roles, scopes := a.principal(ctx)
+	allowed, reason := policy.Check(fullMethod, roles, scopes)
+	if allowed {
+		return nil
+	}
+	if policy.Mode == AuthzModeAudit {
+		a.logger().Warnf(ctx, "authz audit: %s would be denied: %s", fullMethod, reason)
+		return nil
+	}
+	a.logger().Warnf(ctx, "authz denied %s: %s", fullMethod, reason)
+	return status.Errorf(codes.PermissionDenied, "permission denied: %s", reason)
+}
+
+// UnaryServerInterceptor 授权一元拦截器
+func (a *Authorizer) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+		if err = a.Authorize(ctx, info.FullMethod); err != nil {
+			return
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor 授权流拦截器
+func (a *Authorizer) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := a.Authorize(stream.Context(), info.FullMethod); err != nil {
+			return err
+		}
+		return handler(srv, stream)
+	}
+}
+
+// WithServerAuthorizer 启用方法级授权，需放在认证选项之后，日志使用服务端 Log 输出
+func WithServerAuthorizer(authorizer *Authorizer) ServerOption {
+	return func(o *ServerOptions) {
+		o.unaryInterceptors = append(o.unaryInterceptors, authorizer.UnaryServerInterceptor())
+		o.streamInterceptors = append(o.streamInterceptors, authorizer.StreamServerInterceptor())
+		o.hooks = append(o.hooks, func(s *GrpcServer) {
+			authorizer.SetLogger(s.opts.Log)
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package ngrpc
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const testAuthzPolicy = `
+default: deny
+rules:
+  - methods: ["/order.OrderService/Get*"]
+    public: true
+  - methods: ["/order.OrderService/*"]
+    roles: [admin, ops]
+    scopes: [order.write]
+  - methods: ["/user.UserService/*"]
+    scopes: [user.read]
+`
+
+func TestParseAuthzPolicy(t *testing.T) {
+	policy, err := ParseAuthzPolicy([]byte(testAuthzPolicy))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if policy.Default != AuthzDefaultDeny || policy.Mode != AuthzModeEnforce || len(policy.Rules) != 3 {
+		t.Fatalf("policy = %+v", policy)
+	}
+
+	for _, tt := range []struct {
+		name   string
+		policy string
+		err    string
+	}{
+		{"typo", "rules:\n  - methods: [\"/a.B/C\"]\n    role: [admin]\n", "field role not found"},
+		{"no requirement", "rules:\n  - methods: [\"/a.B/C\"]\n", "one of roles, scopes or public: true is required"},
+		{"no methods", "rules:\n  - public: true\n", "methods is required"},
+		{"relative method", "rules:\n  - methods: [\"a.B/C\"]\n    public: true\n", "must be a full method name"},
+		{"default", "default: maybe\n", `default must be "deny" or "allow"`},
+		{"mode", "mode: log\n", `mode must be "enforce" or "audit"`},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseAuthzPolicy([]byte(tt.policy))
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("err = %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestAuthzPolicyCheck(t *testing.T) {
+	policy, err := ParseAuthzPolicy([]byte(testAuthzPolicy))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		method  string
+		roles   []string
+		scopes  []string
+		allowed bool
+	}{
+		{"/order.OrderService/GetOrder", nil, nil, true},
+		{"/order.OrderService/CreateOrder", []string{"ops"}, []string{"order.write"}, true},
+		{"/order.OrderService/CreateOrder", []string{"ops"}, nil, false},
+		{"/order.OrderService/CreateOrder", []string{"guest"}, []string{"order.write"}, false},
+		{"/user.UserService/GetUser", nil, []string{"user.read"}, true},
+		{"/user.UserService/GetUser", []string{"admin"}, nil, false},
+		{"/other.Service/Call", []string{"admin"}, []string{"order.write"}, false},
+	} {
+		allowed, reason := policy.Check(tt.method, tt.roles, tt.scopes)
+		if allowed != tt.allowed {
+			t.Errorf("Check(%s, %v, %v) = %v (%s), want %v", tt.method, tt.roles, tt.scopes, allowed, reason, tt.allowed)
+		}
+	}
+
+	policy.Default = AuthzDefaultAllow
+	if allowed, _ := policy.Check("/other.Service/Call", nil, nil); !allowed {
+		t.Error("default allow: unmatched method denied")
+	}
+}
+
+func TestAuthorizerAudit(t *testing.T) {
+	policy, err := ParseAuthzPolicy([]byte("mode: audit\n" + testAuthzPolicy))
+	if err != nil {
+		t.Fatal(err)
+	}
+	log := new(recordLogger)
+	authorizer := NewAuthorizer(policy, func(ctx context.Context) (roles, scopes []string) { return })
+	authorizer.SetLogger(log)
+	ctx := context.Background()
+	if err := authorizer.Authorize(ctx, "/order.OrderService/CreateOrder"); err != nil {
+		t.Fatalf("audit mode denied: %v", err)
+	}
+	if lines := log.Lines(); len(lines) != 1 || !strings.Contains(lines[0], "would be denied") {
+		t.Fatalf("log = %q", lines)
+	}
+
+	policy.Mode = AuthzModeEnforce
+	if err := authorizer.Authorize(ctx, "/order.OrderService/CreateOrder"); status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("enforce: err = %v", err)
+	}
+	// 健康检查和反射不经过策略
+	if err := authorizer.Authorize(ctx, "/grpc.health.v1.Health/Check"); err != nil {
+		t.Fatalf("health: %v", err)
+	}
+}
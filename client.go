@@ -2,17 +2,18 @@ package ngrpc
 
 import (
 	"context"
-	"time"
+	"sync/atomic"
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
-	"google.golang.org/grpc/keepalive"
 )
 
 // GrpcClient grpc客户端
 type GrpcClient struct {
-	conn *grpc.ClientConn // 连接
-	opts ClientOptions
+	conn     *grpc.ClientConn // 连接
+	opts     ClientOptions
+	timeouts atomic.Pointer[ClientTimeouts]
+	retry    atomic.Pointer[retryPolicy]
 }
 
 // GetConn 获取客户端连接
@@ -26,44 +27,62 @@ func (c *GrpcClient) Close(ctx context.Context) {
 		c.opts.Log.Fatalf(ctx, "close %s grpc client is nil", c.opts.Address)
 		return
 	}
-	err := c.conn.Close()
-	if err != nil {
+	if err := c.close(); err != nil {
 		c.opts.Log.Fatalf(ctx, "close %s grpc client: %v", c.opts.Address, err)
-		return
+	}
+}
+
+// close 关闭连接和服务发现
+func (c *GrpcClient) close() error {
+	if c.conn == nil {
+		return nil
+	}
+	if err := c.conn.Close(); err != nil {
+		return err
 	}
 	if c.opts.discovery != nil {
-		err = c.opts.discovery.Close()
-		if err != nil {
-			c.opts.Log.Fatalf(ctx, "close %s grpc client: %v", c.opts.Address, err)
-			return
-		}
+		return c.opts.discovery.Close()
 	}
+	return nil
 }
 
 // NewGrpcClient 创建Grpc客户端
 func NewGrpcClient(ctx context.Context, opts ...ClientOption) *GrpcClient {
 	client := new(GrpcClient)
 	client.opts = NewClientOptions(opts...)
+	creds := client.opts.Credentials
+	if creds == nil {
+		creds = insecure.NewCredentials()
+	}
 	grpcClientOptions := []grpc.DialOption{
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
-		grpc.WithKeepaliveParams(
-			keepalive.ClientParameters{
-				Time:                2 * time.Minute,  // 每2分钟发送一次 ping
-				Timeout:             10 * time.Second, // 超过10秒无响应就断开
-				PermitWithoutStream: true,             // 即使没有 RPC 也发送 ping
-			},
-		),
+		grpc.WithTransportCredentials(creds),
+		grpc.WithKeepaliveParams(client.opts.Keepalive),
 	}
-	if len(client.opts.StreamClientInterceptors) > 0 {
-		for _, v := range client.opts.StreamClientInterceptors {
-			grpcClientOptions = append(grpcClientOptions, grpc.WithStreamInterceptor(v))
-		}
+	if client.opts.PerRPCCredentials != nil {
+		grpcClientOptions = append(grpcClientOptions, grpc.WithPerRPCCredentials(client.opts.PerRPCCredentials))
+	}
+	if handler := client.opts.clientStatsHandler(); handler != nil {
+		grpcClientOptions = append(grpcClientOptions, grpc.WithStatsHandler(handler))
 	}
-	if len(client.opts.UnaryClientInterceptors) > 0 {
-		for _, v := range client.opts.UnaryClientInterceptors {
-			grpcClientOptions = append(grpcClientOptions, grpc.WithUnaryInterceptor(v))
+	if policy := client.opts.KeepalivePolicy; policy != nil {
+		if err := ValidateKeepalive(client.opts.Keepalive, *policy); err != nil {
+			client.opts.Log.Fatalf(ctx, "%s grpc client keepalive: %v", client.opts.Name, err)
 		}
 	}
+	client.SetTimeouts(client.opts.Timeouts)
+	if err := client.SetRetryPolicy(client.opts.RetryPolicy); err != nil {
+		client.opts.Log.Fatalf(ctx, "%s grpc client: %v", client.opts.Name, err)
+	}
+	streamClientInterceptors := append([]grpc.StreamClientInterceptor{client.timeoutStreamClientInterceptor()}, client.opts.streamInterceptors...)
+	streamClientInterceptors = append(streamClientInterceptors, client.opts.StreamClientInterceptors...)
+	if len(streamClientInterceptors) > 0 {
+		grpcClientOptions = append(grpcClientOptions, grpc.WithChainStreamInterceptor(streamClientInterceptors...))
+	}
+	unaryClientInterceptors := append([]grpc.UnaryClientInterceptor{client.timeoutUnaryClientInterceptor(), client.retryUnaryClientInterceptor()}, client.opts.unaryInterceptors...)
+	unaryClientInterceptors = append(unaryClientInterceptors, client.opts.UnaryClientInterceptors...)
+	if len(unaryClientInterceptors) > 0 {
+		grpcClientOptions = append(grpcClientOptions, grpc.WithChainUnaryInterceptor(unaryClientInterceptors...))
+	}
 	if len(client.opts.dialOptions) > 0 {
 		grpcClientOptions = append(grpcClientOptions, client.opts.dialOptions...)
 	}
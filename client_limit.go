@@ -0,0 +1,196 @@
+package ngrpc
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ClientLimitOptions 客户端限流和并发隔离参数
+type ClientLimitOptions struct {
+	// QPS 每秒请求数，小于等于0时不限制
+	QPS float64
+	// Burst 令牌桶容量，默认1
+	Burst int
+	// MaxConcurrent 最大并发调用数，小于等于0时不限制
+	MaxConcurrent int
+	// MaxWait 最长排队时间，不超过调用剩余的 deadline，为0时不排队
+	MaxWait time.Duration
+	// PerMethod 为 true 时每个方法分别限制
+	PerMethod bool
+}
+
+type bulkhead struct {
+	limiter *rate.Limiter
+	sem     chan struct{}
+}
+
+// ClientLimiter 客户端限流和并发隔离，保护下游服务
+type ClientLimiter struct {
+	opts ClientLimitOptions
+
+	mu      sync.Mutex
+	global  *bulkhead
+	methods map[string]*bulkhead
+}
+
+// NewClientLimiter 创建客户端限流
+func NewClientLimiter(opts ClientLimitOptions) *ClientLimiter {
+	l := &ClientLimiter{
+		opts:    opts,
+		methods: make(map[string]*bulkhead),
+	}
+	if !opts.PerMethod {
+		l.global = l.newBulkhead()
+	}
+	return l
+}
+
+func (l *ClientLimiter) newBulkhead() *bulkhead {
+	b := new(bulkhead)
+	if l.opts.QPS > 0 {
+		b.limiter = RateLimit{Rate: l.opts.QPS, Burst: l.opts.Burst}.newLimiter()
+	}
+	if l.opts.MaxConcurrent > 0 {
+		b.sem = make(chan struct{}, l.opts.MaxConcurrent)
+	}
+	return b
+}
+
+func (l *ClientLimiter) bulkhead(method string) *bulkhead {
+	if l.global != nil {
+		return l.global
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	b, ok := l.methods[method]
+	if !ok {
+		b = l.newBulkhead()
+		l.methods[method] = b
+	}
+	return b
+}
+
+// Acquire 获取调用许可，排队时间超过 MaxWait 或剩余 deadline 时直接失败，成功时需调用 release
+func (l *ClientLimiter) Acquire(ctx context.Context, method string) (release func(), err error) {
+	b := l.bulkhead(method)
+	start := time.Now()
+	deadline := start.Add(l.opts.MaxWait)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	if b.limiter != nil {
+		res := b.limiter.ReserveN(start, 1)
+		if !res.OK() || start.Add(res.DelayFrom(start)).After(deadline) {
+			res.CancelAt(start)
+			return nil, status.Errorf(codes.ResourceExhausted, "client rate limit exceeded for %s", method)
+		}
+		if err = sleepContext(ctx, res.DelayFrom(start)); err != nil {
+			res.Cancel()
+			return nil, status.FromContextError(err).Err()
+		}
+	}
+	release = func() {}
+	if b.sem == nil {
+		return
+	}
+	select {
+	case b.sem <- struct{}{}:
+	default:
+		wait := time.Until(deadline)
+		if wait <= 0 {
+			return nil, status.Errorf(codes.ResourceExhausted, "client concurrency limit exceeded for %s", method)
+		}
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		select {
+		case b.sem <- struct{}{}:
+		case <-timer.C:
+			return nil, status.Errorf(codes.ResourceExhausted, "client concurrency limit exceeded for %s", method)
+		case <-ctx.Done():
+			return nil, status.FromContextError(ctx.Err()).Err()
+		}
+	}
+	var once sync.Once
+	release = func() {
+		once.Do(func() {
+			<-b.sem
+		})
+	}
+	return
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// UnaryClientInterceptor 客户端限流一元拦截器
+func (l *ClientLimiter) UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		release, err := l.Acquire(ctx, method)
+		if err != nil {
+			return err
+		}
+		defer release()
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+// StreamClientInterceptor 客户端限流流拦截器，流结束后释放并发许可
+func (l *ClientLimiter) StreamClientInterceptor() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		release, err := l.Acquire(ctx, method)
+		if err != nil {
+			return nil, err
+		}
+		stream, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			release()
+			return nil, err
+		}
+		stop := context.AfterFunc(ctx, release)
+		return &releaseClientStream{ClientStream: stream, serverStreams: desc.ServerStreams, release: func() {
+			stop()
+			release()
+		}}, nil
+	}
+}
+
+// releaseClientStream RecvMsg 返回错误（包括 io.EOF）时流结束，
+// 服务端非流式时收到唯一的响应后流结束
+type releaseClientStream struct {
+	grpc.ClientStream
+	serverStreams bool
+	release       func()
+}
+
+func (s *releaseClientStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+	if err != nil || !s.serverStreams {
+		s.release()
+	}
+	return err
+}
+
+// WithClientLimiter 启用客户端限流和并发隔离
+func WithClientLimiter(limiter *ClientLimiter) ClientOption {
+	return func(o *ClientOptions) {
+		o.unaryInterceptors = append(o.unaryInterceptors, limiter.UnaryClientInterceptor())
+		o.streamInterceptors = append(o.streamInterceptors, limiter.StreamClientInterceptor())
+	}
+}
@@ -0,0 +1,38 @@
+package ngrpc
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestClientLimiterReleasesClientStream(t *testing.T) {
+	const size = 2
+	limiter := NewClientLimiter(ClientLimitOptions{MaxConcurrent: size})
+	server := startEchoServer(t)
+	conn := dialEcho(t, server, grpc.WithChainStreamInterceptor(limiter.StreamClientInterceptor()))
+	desc := &grpc.StreamDesc{StreamName: "ClientStream", ClientStreams: true}
+	// ctx 不会取消，许可只能由流结束时释放
+	ctx := context.Background()
+	for i := 0; i < size+1; i++ {
+		stream, err := conn.NewStream(ctx, desc, echoClientStream)
+		if err != nil {
+			t.Fatalf("call %d: %v", i, err)
+		}
+		if err = stream.SendMsg(wrapperspb.String("ping")); err != nil {
+			t.Fatalf("call %d: send: %v", i, err)
+		}
+		if err = stream.CloseSend(); err != nil {
+			t.Fatalf("call %d: close send: %v", i, err)
+		}
+		reply := new(wrapperspb.StringValue)
+		if err = stream.RecvMsg(reply); err != nil {
+			t.Fatalf("call %d: recv: %v", i, err)
+		}
+		if reply.GetValue() != "ping" {
+			t.Fatalf("call %d: got %q, want %q", i, reply.GetValue(), "ping")
+		}
+	}
+}
@@ -0,0 +1,602 @@
+package ngrpc
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nilorg/ngrpc/v2/resolver"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/keepalive"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// ServerEnvPrefix 服务端配置环境变量前缀，如 NGRPC_SERVER_ADDRESS、NGRPC_SERVER_REGISTRY_ENDPOINTS
+	ServerEnvPrefix = "NGRPC_SERVER"
+	// ClientEnvPrefix 客户端配置环境变量前缀，如 NGRPC_CLIENT_ADDRESS、NGRPC_CLIENT_TIMEOUTS_DEFAULT
+	ClientEnvPrefix = "NGRPC_CLIENT"
+)
+
+// TLSConfig 证书配置
+type TLSConfig struct {
+	CertFile string `yaml:"cert_file" json:"cert_file"`
+	KeyFile  string `yaml:"key_file" json:"key_file"`
+	// CAFile 服务端用于校验客户端证书（设置后要求客户端证书），客户端用于校验服务端证书
+	CAFile string `yaml:"ca_file" json:"ca_file"`
+	// ServerName 客户端校验的服务端证书名称
+	ServerName string `yaml:"server_name" json:"server_name"`
+}
+
+func (c *TLSConfig) validate(server bool) (errs []error) {
+	if (c.CertFile == "") != (c.KeyFile == "") {
+		errs = append(errs, errors.New("tls: cert_file and key_file must be set together"))
+	}
+	if server && c.CertFile == "" {
+		errs = append(errs, errors.New("tls: cert_file is required"))
+	}
+	return
+}
+
+func (c *TLSConfig) config() (*tls.Config, error) {
+	config := &tls.Config{ServerName: c.ServerName, MinVersion: tls.VersionTLS12}
+	if c.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("tls: %w", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	if c.CAFile != "" {
+		pem, err := os.ReadFile(c.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("tls: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("tls: no certificates found in %s", c.CAFile)
+		}
+		config.RootCAs = pool
+		config.ClientCAs = pool
+	}
+	return config, nil
+}
+
+func (c *TLSConfig) serverCredentials() (credentials.TransportCredentials, error) {
+	config, err := c.config()
+	if err != nil {
+		return nil, err
+	}
+	if config.ClientCAs != nil {
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return credentials.NewTLS(config), nil
+}
+
+func (c *TLSConfig) clientCredentials() (credentials.TransportCredentials, error) {
+	config, err := c.config()
+	if err != nil {
+		return nil, err
+	}
+	return credentials.NewTLS(config), nil
+}
+
+// EtcdConfig etcd 注册中心配置
+type EtcdConfig struct {
+	Endpoints   []string      `yaml:"endpoints" json:"endpoints"`
+	Domain      string        `yaml:"domain" json:"domain"`
+	DialTimeout time.Duration `yaml:"dial_timeout" json:"dial_timeout"`
+	Username    string        `yaml:"username" json:"username"`
+	Password    string        `yaml:"password" json:"password"`
+}
+
+func (c *EtcdConfig) validate(field string) (errs []error) {
+	if len(c.Endpoints) == 0 {
+		errs = append(errs, fmt.Errorf("%s: endpoints is required", field))
+	}
+	if c.Domain == "" {
+		errs = append(errs, fmt.Errorf("%s: domain is required", field))
+	}
+	if c.DialTimeout < 0 {
+		errs = append(errs, fmt.Errorf("%s: dial_timeout must not be negative", field))
+	}
+	return
+}
+
+func (c *EtcdConfig) newClient() (*clientv3.Client, error) {
+	dialTimeout := c.DialTimeout
+	if dialTimeout == 0 {
+		dialTimeout = 5 * time.Second
+	}
+	return clientv3.New(clientv3.Config{
+		Endpoints:   c.Endpoints,
+		DialTimeout: dialTimeout,
+		Username:    c.Username,
+		Password:    c.Password,
+	})
+}
+
+// etcdRegistry 关闭时同时关闭配置创建的 etcd 客户端
+type etcdRegistry struct {
+	*resolver.EtcdRegistry
+	client *clientv3.Client
+}
+
+func (r *etcdRegistry) Close() error {
+	return errors.Join(r.EtcdRegistry.Close(), r.client.Close())
+}
+
+// etcdDiscovery 关闭时同时关闭配置创建的 etcd 客户端
+type etcdDiscovery struct {
+	*resolver.EtcdDiscovery
+	client *clientv3.Client
+}
+
+func (d *etcdDiscovery) Close() error {
+	return errors.Join(d.EtcdDiscovery.Close(), d.client.Close())
+}
+
+// ServerKeepaliveConfig 服务端 keepalive 配置，为零的字段使用默认值
+type ServerKeepaliveConfig struct {
+	Time                  time.Duration `yaml:"time" json:"time"`
+	Timeout               time.Duration `yaml:"timeout" json:"timeout"`
+	MaxConnectionIdle     time.Duration `yaml:"max_connection_idle" json:"max_connection_idle"`
+	MaxConnectionAge      time.Duration `yaml:"max_connection_age" json:"max_connection_age"`
+	MaxConnectionAgeGrace time.Duration `yaml:"max_connection_age_grace" json:"max_connection_age_grace"`
+	// MinTime 允许客户端 ping 的最小间隔
+	MinTime             time.Duration `yaml:"min_time" json:"min_time"`
+	PermitWithoutStream *bool         `yaml:"permit_without_stream" json:"permit_without_stream"`
+}
+
+// ClientKeepaliveConfig 客户端 keepalive 配置，为零的字段使用 DefaultClientKeepalive
+type ClientKeepaliveConfig struct {
+	Time                time.Duration `yaml:"time" json:"time"`
+	Timeout             time.Duration `yaml:"timeout" json:"timeout"`
+	PermitWithoutStream *bool         `yaml:"permit_without_stream" json:"permit_without_stream"`
+}
+
+// ServerConfig 服务端配置文件
+type ServerConfig struct {
+	Name                 string                 `yaml:"name" json:"name"`
+	Address              string                 `yaml:"address" json:"address"`
+	RandomPort           bool                   `yaml:"random_port" json:"random_port"`
+	LogLevel             string                 `yaml:"log_level" json:"log_level"`
+	TLS                  *TLSConfig             `yaml:"tls" json:"tls"`
+	Keepalive            *ServerKeepaliveConfig `yaml:"keepalive" json:"keepalive"`
+	MaxRecvMsgSize       int                    `yaml:"max_recv_msg_size" json:"max_recv_msg_size"`
+	MaxSendMsgSize       int                    `yaml:"max_send_msg_size" json:"max_send_msg_size"`
+	MaxConcurrentStreams uint32                 `yaml:"max_concurrent_streams" json:"max_concurrent_streams"`
+	Registry             *EtcdConfig            `yaml:"registry" json:"registry"`
+}
+
+// ClientConfig 客户端配置文件
+type ClientConfig struct {
+	Name      string                 `yaml:"name" json:"name"`
+	Address   string                 `yaml:"address" json:"address"`
+	LogLevel  string                 `yaml:"log_level" json:"log_level"`
+	TLS       *TLSConfig             `yaml:"tls" json:"tls"`
+	Keepalive *ClientKeepaliveConfig `yaml:"keepalive" json:"keepalive"`
+	Discovery *EtcdConfig            `yaml:"discovery" json:"discovery"`
+	Timeouts  ClientTimeouts         `yaml:"timeouts" json:"timeouts"`
+	Retry     *RetryPolicy           `yaml:"retry" json:"retry"`
+}
+
+// LoadServerConfig 从 YAML/JSON 文件和 NGRPC_SERVER_* 环境变量创建服务端参数，path 为空时只读取环境变量
+func LoadServerConfig(path string) ([]ServerOption, error) {
+	config, err := ReadServerConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	return config.Options(context.Background())
+}
+
+// LoadClientConfig 从 YAML/JSON 文件和 NGRPC_CLIENT_* 环境变量创建客户端参数，path 为空时只读取环境变量
+func LoadClientConfig(path string) ([]ClientOption, error) {
+	config, err := ReadClientConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	return config.Options()
+}
+
+// ReadServerConfig 读取并校验服务端配置，环境变量优先于文件
+func ReadServerConfig(path string) (*ServerConfig, error) {
+	data, err := readConfigFile(path)
+	if err != nil {
+		return nil, err
+	}
+	config, err := ParseServerConfig(data)
+	if err != nil {
+		return nil, fmt.Errorf("server config %s: %w", path, err)
+	}
+	return config, nil
+}
+
+// ParseServerConfig 解析并校验服务端配置，环境变量优先于 data
+func ParseServerConfig(data []byte) (*ServerConfig, error) {
+	config := new(ServerConfig)
+	if err := parseConfig(data, ServerEnvPrefix, config); err != nil {
+		return nil, err
+	}
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+	return config, nil
+}
+
+// ReadClientConfig 读取并校验客户端配置，环境变量优先于文件
+func ReadClientConfig(path string) (*ClientConfig, error) {
+	data, err := readConfigFile(path)
+	if err != nil {
+		return nil, err
+	}
+	config, err := ParseClientConfig(data)
+	if err != nil {
+		return nil, fmt.Errorf("client config %s: %w", path, err)
+	}
+	return config, nil
+}
+
+// ParseClientConfig 解析并校验客户端配置，环境变量优先于 data
+func ParseClientConfig(data []byte) (*ClientConfig, error) {
+	config := new(ClientConfig)
+	if err := parseConfig(data, ClientEnvPrefix, config); err != nil {
+		return nil, err
+	}
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+	return config, nil
+}
+
+func readConfigFile(path string) ([]byte, error) {
+	if path == "" {
+		return nil, nil
+	}
+	return os.ReadFile(path)
+}
+
+// parseConfig 解析配置（JSON 按 YAML 解析），不允许未知字段，然后应用环境变量
+func parseConfig(data []byte, envPrefix string, config interface{}) error {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(config); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("parse config: %w", err)
+	}
+	if err := applyEnv(envPrefix, reflect.ValueOf(config).Elem()); err != nil {
+		return fmt.Errorf("config environment: %w", err)
+	}
+	return nil
+}
+
+// applyEnv 按 yaml 字段名将 PREFIX_FIELD_SUBFIELD 环境变量写入配置，切片以逗号分隔，不支持 map
+func applyEnv(prefix string, v reflect.Value) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		tag, _, _ := strings.Cut(t.Field(i).Tag.Get("yaml"), ",")
+		if tag == "" || tag == "-" {
+			continue
+		}
+		name := prefix + "_" + strings.ToUpper(tag)
+		field := v.Field(i)
+		switch {
+		case field.Kind() == reflect.Map:
+			continue
+		case field.Kind() == reflect.Struct:
+			if err := applyEnv(name, field); err != nil {
+				return err
+			}
+			continue
+		case field.Kind() == reflect.Ptr && field.Type().Elem().Kind() == reflect.Struct:
+			if !hasEnvPrefix(name + "_") {
+				continue
+			}
+			if field.IsNil() {
+				field.Set(reflect.New(field.Type().Elem()))
+			}
+			if err := applyEnv(name, field.Elem()); err != nil {
+				return err
+			}
+			continue
+		}
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			continue
+		}
+		if err := setEnvValue(field, value); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func hasEnvPrefix(prefix string) bool {
+	for _, kv := range os.Environ() {
+		if strings.HasPrefix(kv, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+func setEnvValue(field reflect.Value, value string) error {
+	if field.Kind() == reflect.Ptr {
+		elem := reflect.New(field.Type().Elem())
+		if err := setEnvValue(elem.Elem(), value); err != nil {
+			return err
+		}
+		field.Set(elem)
+		return nil
+	}
+	if field.Type() == durationType {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(d))
+		return nil
+	}
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetInt(n)
+	case reflect.Uint, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetUint(n)
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return err
+		}
+		field.SetFloat(f)
+	case reflect.Slice:
+		if field.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported type %s", field.Type())
+		}
+		var items []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		field.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported type %s", field.Type())
+	}
+	return nil
+}
+
+// validateAddress 校验 host:port、unix:///path.sock 或 systemd:[name] 地址
+func validateAddress(address string) error {
+	if strings.HasPrefix(address, unixScheme) || strings.HasPrefix(address, systemdScheme) {
+		return nil
+	}
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		return fmt.Errorf("address: %w", err)
+	}
+	return nil
+}
+
+func validateLogLevel(level string) error {
+	if level == "" {
+		return nil
+	}
+	if _, err := ParseLogLevel(level); err != nil {
+		return fmt.Errorf("log_level: %w", err)
+	}
+	return nil
+}
+
+// Validate 校验服务端配置
+func (c *ServerConfig) Validate() error {
+	var errs []error
+	if c.Address != "" {
+		if err := validateAddress(c.Address); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := validateLogLevel(c.LogLevel); err != nil {
+		errs = append(errs, err)
+	}
+	if c.TLS != nil {
+		errs = append(errs, c.TLS.validate(true)...)
+	}
+	if k := c.Keepalive; k != nil {
+		if k.Time < 0 || k.Timeout < 0 || k.MaxConnectionIdle < 0 || k.MaxConnectionAge < 0 || k.MaxConnectionAgeGrace < 0 || k.MinTime < 0 {
+			errs = append(errs, errors.New("keepalive: durations must not be negative"))
+		}
+	}
+	if c.MaxRecvMsgSize < 0 {
+		errs = append(errs, errors.New("max_recv_msg_size must not be negative"))
+	}
+	if c.MaxSendMsgSize < 0 {
+		errs = append(errs, errors.New("max_send_msg_size must not be negative"))
+	}
+	if c.Registry != nil {
+		errs = append(errs, c.Registry.validate("registry")...)
+	}
+	return errors.Join(errs...)
+}
+
+// Validate 校验客户端配置
+func (c *ClientConfig) Validate() error {
+	var errs []error
+	if c.Address != "" && c.Discovery == nil {
+		if err := validateAddress(c.Address); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := validateLogLevel(c.LogLevel); err != nil {
+		errs = append(errs, err)
+	}
+	if c.TLS != nil {
+		errs = append(errs, c.TLS.validate(false)...)
+	}
+	if k := c.Keepalive; k != nil && (k.Time < 0 || k.Timeout < 0) {
+		errs = append(errs, errors.New("keepalive: durations must not be negative"))
+	}
+	if c.Discovery != nil {
+		errs = append(errs, c.Discovery.validate("discovery")...)
+		if c.Name == "" {
+			errs = append(errs, errors.New("name is required when discovery is set"))
+		}
+	}
+	if c.Timeouts.Default < 0 {
+		errs = append(errs, errors.New("timeouts: default must not be negative"))
+	}
+	for method, timeout := range c.Timeouts.Methods {
+		if timeout < 0 {
+			errs = append(errs, fmt.Errorf("timeouts: %s must not be negative", method))
+		}
+	}
+	if c.Retry != nil {
+		if err := c.Retry.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("retry: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// newConfigLogger 按日志级别创建日志
+func newConfigLogger(level string) *StdLogger {
+	logLevel, _ := ParseLogLevel(level)
+	return NewStdLogger(logLevel, os.Stderr, false)
+}
+
+// Options 转换为服务端参数，配置了注册中心时创建 etcd 客户端，随注册中心关闭
+func (c *ServerConfig) Options(ctx context.Context) ([]ServerOption, error) {
+	var opts []ServerOption
+	if c.Name != "" {
+		opts = append(opts, WithServerName(c.Name))
+	}
+	if c.Address != "" {
+		opts = append(opts, WithServerAddress(c.Address))
+	}
+	if c.RandomPort {
+		opts = append(opts, WithServerRandomPort(true))
+	}
+	if c.LogLevel != "" {
+		opts = append(opts, WithServerLogger(newConfigLogger(c.LogLevel)))
+	}
+	if c.TLS != nil {
+		creds, err := c.TLS.serverCredentials()
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, WithServerCredentials(creds))
+	}
+	if k := c.Keepalive; k != nil {
+		policy := DefaultServerKeepalivePolicy
+		if k.MinTime > 0 {
+			policy.MinTime = k.MinTime
+		}
+		if k.PermitWithoutStream != nil {
+			policy.PermitWithoutStream = *k.PermitWithoutStream
+		}
+		opts = append(opts, WithServerKeepalivePolicy(policy))
+		opts = append(opts, WithServerKeepalive(keepalive.ServerParameters{
+			Time:                  k.Time,
+			Timeout:               k.Timeout,
+			MaxConnectionIdle:     k.MaxConnectionIdle,
+			MaxConnectionAge:      k.MaxConnectionAge,
+			MaxConnectionAgeGrace: k.MaxConnectionAgeGrace,
+		}))
+	}
+	if c.MaxRecvMsgSize > 0 {
+		opts = append(opts, WithServerMaxRecvMsgSize(c.MaxRecvMsgSize))
+	}
+	if c.MaxSendMsgSize > 0 {
+		opts = append(opts, WithServerMaxSendMsgSize(c.MaxSendMsgSize))
+	}
+	if c.MaxConcurrentStreams > 0 {
+		opts = append(opts, WithServerMaxConcurrentStreams(c.MaxConcurrentStreams))
+	}
+	if c.Registry != nil {
+		client, err := c.Registry.newClient()
+		if err != nil {
+			return nil, fmt.Errorf("registry: %w", err)
+		}
+		registry := &etcdRegistry{EtcdRegistry: resolver.NewEtcdRegistry(ctx, client, c.Registry.Domain), client: client}
+		opts = append(opts, WithServerRegister(registry))
+	}
+	return opts, nil
+}
+
+// Options 转换为客户端参数，配置了服务发现时创建 etcd 客户端，随客户端关闭
+func (c *ClientConfig) Options() ([]ClientOption, error) {
+	var opts []ClientOption
+	if c.Name != "" {
+		opts = append(opts, WithClientName(c.Name))
+	}
+	if c.Address != "" {
+		opts = append(opts, WithClientAddress(c.Address))
+	}
+	if c.LogLevel != "" {
+		opts = append(opts, WithClientLogger(newConfigLogger(c.LogLevel)))
+	}
+	if c.TLS != nil {
+		creds, err := c.TLS.clientCredentials()
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, WithClientCredentials(creds))
+	}
+	if k := c.Keepalive; k != nil {
+		params := DefaultClientKeepalive
+		if k.Time > 0 {
+			params.Time = k.Time
+		}
+		if k.Timeout > 0 {
+			params.Timeout = k.Timeout
+		}
+		if k.PermitWithoutStream != nil {
+			params.PermitWithoutStream = *k.PermitWithoutStream
+		}
+		opts = append(opts, WithClientKeepalive(params))
+	}
+	if c.Timeouts.Default > 0 {
+		opts = append(opts, WithClientDefaultTimeout(c.Timeouts.Default))
+	}
+	if len(c.Timeouts.Methods) > 0 {
+		opts = append(opts, WithClientMethodTimeouts(c.Timeouts.Methods))
+	}
+	if c.Retry != nil {
+		opts = append(opts, WithClientRetryPolicy(*c.Retry))
+	}
+	if c.Discovery != nil {
+		client, err := c.Discovery.newClient()
+		if err != nil {
+			return nil, fmt.Errorf("discovery: %w", err)
+		}
+		discovery := &etcdDiscovery{EtcdDiscovery: resolver.NewEtcdDiscovery(client, c.Discovery.Domain), client: client}
+		opts = append(opts, WithClientDiscovery(discovery))
+	}
+	return opts, nil
+}
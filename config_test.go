@@ -0,0 +1,373 @@
+package ngrpc
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/keepalive"
+)
+
+func boolPtr(b bool) *bool {
+	return &b
+}
+
+func TestParseServerConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		env  map[string]string
+		want *ServerConfig
+		// errs 错误信息应包含的全部内容
+		errs []string
+	}{
+		{
+			name: "yaml",
+			data: `
+name: order
+address: ":8080"
+keepalive:
+  time: 30s
+  max_connection_age: 1h
+  permit_without_stream: false
+max_recv_msg_size: 1048576
+registry:
+  endpoints: [a:2379, b:2379]
+  domain: prod
+  dial_timeout: 3s
+`,
+			want: &ServerConfig{
+				Name:           "order",
+				Address:        ":8080",
+				Keepalive:      &ServerKeepaliveConfig{Time: 30 * time.Second, MaxConnectionAge: time.Hour, PermitWithoutStream: boolPtr(false)},
+				MaxRecvMsgSize: 1 << 20,
+				Registry:       &EtcdConfig{Endpoints: []string{"a:2379", "b:2379"}, Domain: "prod", DialTimeout: 3 * time.Second},
+			},
+		},
+		{
+			name: "json",
+			data: `{"name": "order", "address": "unix:///run/order.sock", "random_port": true, "keepalive": {"min_time": "10s"}}`,
+			want: &ServerConfig{Name: "order", Address: "unix:///run/order.sock", RandomPort: true, Keepalive: &ServerKeepaliveConfig{MinTime: 10 * time.Second}},
+		},
+		{
+			name: "env overrides file",
+			data: "name: order\naddress: \":8080\"\nregistry: {endpoints: [a:2379], domain: prod}\n",
+			env: map[string]string{
+				"NGRPC_SERVER_ADDRESS":                         ":9090",
+				"NGRPC_SERVER_REGISTRY_ENDPOINTS":              "c:2379, d:2379,",
+				"NGRPC_SERVER_KEEPALIVE_TIME":                  "1m",
+				"NGRPC_SERVER_KEEPALIVE_PERMIT_WITHOUT_STREAM": "true",
+				"NGRPC_SERVER_MAX_CONCURRENT_STREAMS":          "100",
+			},
+			want: &ServerConfig{
+				Name:                 "order",
+				Address:              ":9090",
+				Keepalive:            &ServerKeepaliveConfig{Time: time.Minute, PermitWithoutStream: boolPtr(true)},
+				MaxConcurrentStreams: 100,
+				Registry:             &EtcdConfig{Endpoints: []string{"c:2379", "d:2379"}, Domain: "prod"},
+			},
+		},
+		{
+			name: "env only",
+			env:  map[string]string{"NGRPC_SERVER_NAME": "order", "NGRPC_SERVER_TLS_CERT_FILE": "a.pem", "NGRPC_SERVER_TLS_KEY_FILE": "a.key"},
+			want: &ServerConfig{Name: "order", TLS: &TLSConfig{CertFile: "a.pem", KeyFile: "a.key"}},
+		},
+		{
+			name: "unknown field",
+			data: "name: order\nadress: \":8080\"\n",
+			errs: []string{"parse config", "field adress not found"},
+		},
+		{
+			name: "unknown nested field",
+			data: "keepalive: {interval: 1s}\n",
+			errs: []string{"field interval not found"},
+		},
+		{
+			name: "invalid duration",
+			data: "keepalive: {time: soon}\n",
+			errs: []string{"parse config"},
+		},
+		{
+			name: "invalid env duration",
+			env:  map[string]string{"NGRPC_SERVER_KEEPALIVE_TIME": "soon"},
+			errs: []string{"config environment", "NGRPC_SERVER_KEEPALIVE_TIME"},
+		},
+		{
+			name: "invalid env number",
+			env:  map[string]string{"NGRPC_SERVER_MAX_CONCURRENT_STREAMS": "-1"},
+			errs: []string{"NGRPC_SERVER_MAX_CONCURRENT_STREAMS"},
+		},
+		{
+			name: "validate aggregates errors",
+			data: `
+address: localhost
+log_level: loud
+tls: {key_file: a.key}
+keepalive: {timeout: -1s}
+max_recv_msg_size: -1
+max_send_msg_size: -1
+registry: {dial_timeout: -1s}
+`,
+			errs: []string{
+				"invalid config",
+				"address: address localhost: missing port in address",
+				`log_level: unknown log level "loud"`,
+				"tls: cert_file and key_file must be set together",
+				"tls: cert_file is required",
+				"keepalive: durations must not be negative",
+				"max_recv_msg_size must not be negative",
+				"max_send_msg_size must not be negative",
+				"registry: endpoints is required",
+				"registry: domain is required",
+				"registry: dial_timeout must not be negative",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			got, err := ParseServerConfig([]byte(tt.data))
+			checkConfigResult(t, got, tt.want, err, tt.errs)
+		})
+	}
+}
+
+func TestParseClientConfig(t *testing.T) {
+	retry := "retry: {max_attempts: 3, initial_backoff: 100ms, max_backoff: 1s, backoff_multiplier: 2, retryable_status_codes: [UNAVAILABLE]}\n"
+	tests := []struct {
+		name string
+		data string
+		env  map[string]string
+		want *ClientConfig
+		errs []string
+	}{
+		{
+			name: "timeouts and retry",
+			data: "address: localhost:8080\ntimeouts:\n  default: 2s\n  methods: {/pkg.Service/Slow: 10s}\n" + retry,
+			want: &ClientConfig{
+				Address:  "localhost:8080",
+				Timeouts: ClientTimeouts{Default: 2 * time.Second, Methods: map[string]time.Duration{"/pkg.Service/Slow": 10 * time.Second}},
+				Retry: &RetryPolicy{
+					MaxAttempts: 3, InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second,
+					BackoffMultiplier: 2, RetryableStatusCodes: []string{"UNAVAILABLE"},
+				},
+			},
+		},
+		{
+			name: "env overrides file",
+			data: "address: localhost:8080\ntimeouts: {default: 2s, methods: {/pkg.Service/Slow: 10s}}\n",
+			env: map[string]string{
+				"NGRPC_CLIENT_TIMEOUTS_DEFAULT":    "3s",
+				"NGRPC_CLIENT_KEEPALIVE_TIMEOUT":   "5s",
+				"NGRPC_CLIENT_TIMEOUTS_METHODS":    "ignored",
+				"NGRPC_CLIENT_DISCOVERY_DOMAIN":    "prod",
+				"NGRPC_CLIENT_DISCOVERY_ENDPOINTS": "a:2379",
+				"NGRPC_CLIENT_NAME":                "order",
+			},
+			want: &ClientConfig{
+				Name:      "order",
+				Address:   "localhost:8080",
+				Keepalive: &ClientKeepaliveConfig{Timeout: 5 * time.Second},
+				Discovery: &EtcdConfig{Endpoints: []string{"a:2379"}, Domain: "prod"},
+				Timeouts:  ClientTimeouts{Default: 3 * time.Second, Methods: map[string]time.Duration{"/pkg.Service/Slow": 10 * time.Second}},
+			},
+		},
+		{
+			name: "discovery without address check",
+			data: "name: order\naddress: order\ndiscovery: {endpoints: [a:2379], domain: prod}\n",
+			want: &ClientConfig{Name: "order", Address: "order", Discovery: &EtcdConfig{Endpoints: []string{"a:2379"}, Domain: "prod"}},
+		},
+		{
+			name: "unknown field",
+			data: "timeouts: {defualt: 1s}\n",
+			errs: []string{"field defualt not found"},
+		},
+		{
+			name: "validate aggregates errors",
+			data: "discovery: {endpoints: [a:2379], domain: prod}\ntimeouts: {default: -1s, methods: {/pkg.Service/Call: -1s}}\nretry: {max_attempts: 9}\n",
+			errs: []string{
+				"invalid config",
+				"name is required when discovery is set",
+				"timeouts: default must not be negative",
+				"timeouts: /pkg.Service/Call must not be negative",
+				"retry: ",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			got, err := ParseClientConfig([]byte(tt.data))
+			checkConfigResult(t, got, tt.want, err, tt.errs)
+		})
+	}
+}
+
+// checkConfigResult 比较解析结果，errs 不为空时要求错误信息包含全部内容
+func checkConfigResult[T any](t *testing.T, got, want *T, err error, errs []string) {
+	t.Helper()
+	if len(errs) > 0 {
+		if err == nil {
+			t.Fatalf("got %+v, want error", got)
+		}
+		for _, msg := range errs {
+			if !strings.Contains(err.Error(), msg) {
+				t.Fatalf("error %q does not contain %q", err, msg)
+			}
+		}
+		return
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestConfigKeepaliveOptions(t *testing.T) {
+	server := &ServerConfig{Keepalive: &ServerKeepaliveConfig{
+		Time:                  time.Minute,
+		MaxConnectionIdle:     5 * time.Minute,
+		MaxConnectionAge:      time.Hour,
+		MaxConnectionAgeGrace: time.Minute,
+		MinTime:               30 * time.Second,
+	}, MaxRecvMsgSize: 1 << 20, MaxConcurrentStreams: 10}
+	opts, err := server.Options(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	o := NewServerOptions(opts...)
+	wantParams := keepalive.ServerParameters{Time: time.Minute, MaxConnectionIdle: 5 * time.Minute, MaxConnectionAge: time.Hour, MaxConnectionAgeGrace: time.Minute}
+	if o.Keepalive != wantParams {
+		t.Fatalf("keepalive = %+v, want %+v", o.Keepalive, wantParams)
+	}
+	// 未设置的 permit_without_stream 使用默认约束
+	if want := (keepalive.EnforcementPolicy{MinTime: 30 * time.Second, PermitWithoutStream: true}); o.KeepalivePolicy != want {
+		t.Fatalf("policy = %+v, want %+v", o.KeepalivePolicy, want)
+	}
+	if o.MaxRecvMsgSize != 1<<20 || o.MaxConcurrentStreams != 10 {
+		t.Fatalf("max recv %d, max streams %d", o.MaxRecvMsgSize, o.MaxConcurrentStreams)
+	}
+
+	client := &ClientConfig{Keepalive: &ClientKeepaliveConfig{Timeout: 5 * time.Second, PermitWithoutStream: boolPtr(false)}}
+	copts, err := client.Options()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := keepalive.ClientParameters{Time: DefaultClientKeepalive.Time, Timeout: 5 * time.Second}
+	if got := NewClientOptions(copts...).Keepalive; got != want {
+		t.Fatalf("client keepalive = %+v, want %+v", got, want)
+	}
+}
+
+// writeTestCert 在 dir 中写入自签名证书和私钥
+func writeTestCert(t *testing.T, dir string) (certFile, keyFile string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "ngrpc-test"},
+		DNSNames:              []string{"ngrpc-test"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile, keyFile = filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	if err = os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return
+}
+
+func TestConfigTLSOptions(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeTestCert(t, dir)
+	tests := []struct {
+		name    string
+		tls     TLSConfig
+		wantErr string
+	}{
+		{name: "server cert", tls: TLSConfig{CertFile: certFile, KeyFile: keyFile}},
+		{name: "mutual", tls: TLSConfig{CertFile: certFile, KeyFile: keyFile, CAFile: certFile}},
+		{name: "missing cert", tls: TLSConfig{CertFile: filepath.Join(dir, "none.pem"), KeyFile: keyFile}, wantErr: "tls: "},
+		{name: "invalid ca", tls: TLSConfig{CertFile: certFile, KeyFile: keyFile, CAFile: keyFile}, wantErr: "no certificates found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tls := tt.tls
+			opts, err := (&ServerConfig{TLS: &tls}).Options(context.Background())
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if creds := NewServerOptions(opts...).Credentials; creds == nil || creds.Info().SecurityProtocol != "tls" {
+				t.Fatalf("server credentials = %v, want tls", creds)
+			}
+			copts, err := (&ClientConfig{TLS: &TLSConfig{CAFile: certFile, ServerName: "ngrpc-test"}}).Options()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if creds := NewClientOptions(copts...).Credentials; creds == nil || creds.Info().ServerName != "ngrpc-test" {
+				t.Fatalf("client credentials = %v, want tls for ngrpc-test", creds)
+			}
+		})
+	}
+}
+
+func TestReadServerConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "server.yaml")
+	if err := os.WriteFile(path, []byte("address: bad\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	// 错误信息包含文件路径
+	if _, err := ReadServerConfig(path); err == nil || !strings.HasPrefix(err.Error(), "server config "+path+": invalid config") {
+		t.Fatalf("err = %v", err)
+	}
+	if _, err := ReadServerConfig(filepath.Join(t.TempDir(), "missing.yaml")); !os.IsNotExist(err) {
+		t.Fatalf("missing file: %v", err)
+	}
+	// 路径为空时只读取环境变量
+	t.Setenv("NGRPC_SERVER_NAME", "order")
+	config, err := ReadServerConfig("")
+	if err != nil || config.Name != "order" {
+		t.Fatalf("env only: %+v, %v", config, err)
+	}
+}
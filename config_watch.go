@@ -0,0 +1,237 @@
+package ngrpc
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// ConfigSource 配置来源
+type ConfigSource interface {
+	// Watch 读取配置并在每次变化时调用 fn，读取失败时 err 不为nil，阻塞直到 ctx 取消
+	Watch(ctx context.Context, fn func(data []byte, err error)) error
+}
+
+// FileConfigSource 定期检查文件修改时间的配置来源
+type FileConfigSource struct {
+	path     string
+	interval time.Duration
+}
+
+// NewFileConfigSource 创建文件配置来源，interval 为检查间隔，小于等于0时为5秒
+func NewFileConfigSource(path string, interval time.Duration) *FileConfigSource {
+	if interval <= 0 {
+		interval = defaultWatchInterval
+	}
+	return &FileConfigSource{path: path, interval: interval}
+}
+
+func (f *FileConfigSource) Watch(ctx context.Context, fn func(data []byte, err error)) error {
+	fi, err := os.Stat(f.path)
+	if err != nil {
+		return err
+	}
+	modTime := fi.ModTime()
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		return err
+	}
+	fn(data, nil)
+	ticker := time.NewTicker(f.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+		fi, err = os.Stat(f.path)
+		if err != nil {
+			fn(nil, err)
+			continue
+		}
+		if fi.ModTime().Equal(modTime) {
+			continue
+		}
+		modTime = fi.ModTime()
+		data, err = os.ReadFile(f.path)
+		fn(data, err)
+	}
+}
+
+const (
+	// etcdWatchMinBackoff etcd watch 中断后重新读取的初始等待时间
+	etcdWatchMinBackoff = 500 * time.Millisecond
+	// etcdWatchMaxBackoff etcd watch 中断后重新读取的最长等待时间
+	etcdWatchMaxBackoff = 30 * time.Second
+)
+
+// EtcdConfigSource 监听 etcd key 的配置来源
+type EtcdConfigSource struct {
+	client *clientv3.Client
+	key    string
+}
+
+// NewEtcdConfigSource 创建 etcd 配置来源
+func NewEtcdConfigSource(client *clientv3.Client, key string) *EtcdConfigSource {
+	return &EtcdConfigSource{client: client, key: key}
+}
+
+func (e *EtcdConfigSource) Watch(ctx context.Context, fn func(data []byte, err error)) error {
+	resp, err := e.client.Get(ctx, e.key)
+	if err != nil {
+		return err
+	}
+	if len(resp.Kvs) == 0 {
+		return fmt.Errorf("config key %s not found", e.key)
+	}
+	fn(resp.Kvs[0].Value, nil)
+	rev := resp.Header.Revision
+	backoff := etcdWatchMinBackoff
+	for ctx.Err() == nil {
+		for wresp := range e.client.Watch(ctx, e.key, clientv3.WithRev(rev+1)) {
+			if err = wresp.Err(); err != nil {
+				fn(nil, err)
+				break
+			}
+			backoff = etcdWatchMinBackoff
+			for _, ev := range wresp.Events {
+				rev = ev.Kv.ModRevision
+				if ev.Type == clientv3.EventTypeDelete {
+					fn(nil, fmt.Errorf("config key %s deleted", e.key))
+					continue
+				}
+				fn(ev.Kv.Value, nil)
+			}
+		}
+		// watch 中断（如已压缩的版本或 etcd 不可用），按指数退避等待后重新读取当前值
+		if sleepContext(ctx, backoff) != nil {
+			break
+		}
+		backoff = min(backoff*2, etcdWatchMaxBackoff)
+		if resp, err = e.client.Get(ctx, e.key); err != nil {
+			if ctx.Err() == nil {
+				fn(nil, err)
+			}
+			continue
+		}
+		rev = resp.Header.Revision
+		if len(resp.Kvs) > 0 {
+			fn(resp.Kvs[0].Value, nil)
+		}
+	}
+	return ctx.Err()
+}
+
+// changedFields 除 dynamic 之外发生变化的 yaml 字段
+func changedFields(prev, next interface{}, dynamic ...string) (fields []string) {
+	pv, nv := reflect.ValueOf(prev).Elem(), reflect.ValueOf(next).Elem()
+	t := pv.Type()
+	for i := 0; i < t.NumField(); i++ {
+		tag, _, _ := strings.Cut(t.Field(i).Tag.Get("yaml"), ",")
+		if tag == "" || tag == "-" || slices.Contains(dynamic, tag) {
+			continue
+		}
+		if !reflect.DeepEqual(pv.Field(i).Interface(), nv.Field(i).Interface()) {
+			fields = append(fields, tag)
+		}
+	}
+	return
+}
+
+// setLogLevel 修改支持运行时调整级别的日志
+func setLogLevel(log Logger, level string) error {
+	l, ok := log.(interface{ SetLevel(LogLevel) })
+	if !ok {
+		return fmt.Errorf("logger %T does not support changing level", log)
+	}
+	logLevel, err := ParseLogLevel(level)
+	if err != nil {
+		return err
+	}
+	l.SetLevel(logLevel)
+	return nil
+}
+
+// WatchConfig 监听配置变化，应用可在运行时修改的日志级别，其余字段的修改需要重启，只记录日志；
+// 首次读取的配置作为比较的基准，阻塞直到 ctx 取消。允许调用的方法通过 WatchMethodAllowList 单独监听
+func (s *GrpcServer) WatchConfig(ctx context.Context, source ConfigSource) error {
+	var current *ServerConfig
+	return source.Watch(ctx, func(data []byte, err error) {
+		if err == nil {
+			var config *ServerConfig
+			if config, err = ParseServerConfig(data); err == nil {
+				s.ApplyConfig(ctx, current, config)
+				current = config
+				return
+			}
+		}
+		s.opts.Log.Errorf(ctx, "%s config reload failed, keeping previous config: %v", s.opts.Name, err)
+	})
+}
+
+// ApplyConfig 应用 next 中相对 prev 可在运行时修改的部分，prev 为nil时应用 next 中设置的部分
+func (s *GrpcServer) ApplyConfig(ctx context.Context, prev, next *ServerConfig) {
+	if prev == nil {
+		prev = new(ServerConfig)
+	} else if fields := changedFields(prev, next, "log_level"); len(fields) > 0 {
+		s.opts.Log.Warnf(ctx, "%s config change of %s requires restart, ignored", s.opts.Name, strings.Join(fields, ", "))
+	}
+	if next.LogLevel != "" && next.LogLevel != prev.LogLevel {
+		if err := setLogLevel(s.opts.Log, next.LogLevel); err != nil {
+			s.opts.Log.Warnf(ctx, "%s config change of log_level rejected: %v", s.opts.Name, err)
+		} else {
+			s.opts.Log.Infof(ctx, "%s log level set to %s", s.opts.Name, next.LogLevel)
+		}
+	}
+}
+
+// WatchConfig 监听配置变化，应用可在运行时修改的日志级别、默认超时和重试策略，
+// 其余字段的修改需要重启，只记录日志；首次读取的配置作为比较的基准，阻塞直到 ctx 取消
+func (c *GrpcClient) WatchConfig(ctx context.Context, source ConfigSource) error {
+	var current *ClientConfig
+	return source.Watch(ctx, func(data []byte, err error) {
+		if err == nil {
+			var config *ClientConfig
+			if config, err = ParseClientConfig(data); err == nil {
+				c.ApplyConfig(ctx, current, config)
+				current = config
+				return
+			}
+		}
+		c.opts.Log.Errorf(ctx, "%s config reload failed, keeping previous config: %v", c.opts.Name, err)
+	})
+}
+
+// ApplyConfig 应用 next 中相对 prev 可在运行时修改的部分，prev 为nil时应用 next 中设置的部分
+func (c *GrpcClient) ApplyConfig(ctx context.Context, prev, next *ClientConfig) {
+	if prev == nil {
+		prev = new(ClientConfig)
+	} else if fields := changedFields(prev, next, "log_level", "timeouts", "retry"); len(fields) > 0 {
+		c.opts.Log.Warnf(ctx, "%s config change of %s requires restart, ignored", c.opts.Name, strings.Join(fields, ", "))
+	}
+	if next.LogLevel != "" && next.LogLevel != prev.LogLevel {
+		if err := setLogLevel(c.opts.Log, next.LogLevel); err != nil {
+			c.opts.Log.Warnf(ctx, "%s config change of log_level rejected: %v", c.opts.Name, err)
+		} else {
+			c.opts.Log.Infof(ctx, "%s log level set to %s", c.opts.Name, next.LogLevel)
+		}
+	}
+	if !reflect.DeepEqual(prev.Timeouts, next.Timeouts) {
+		c.SetTimeouts(next.Timeouts)
+		c.opts.Log.Infof(ctx, "%s timeouts updated", c.opts.Name)
+	}
+	if !reflect.DeepEqual(prev.Retry, next.Retry) {
+		if err := c.SetRetryPolicy(next.Retry); err != nil {
+			c.opts.Log.Warnf(ctx, "%s config change of retry rejected: %v", c.opts.Name, err)
+		} else {
+			c.opts.Log.Infof(ctx, "%s retry policy updated", c.opts.Name)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package ngrpc
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ClientTimeouts 客户端默认超时，仅在调用上下文没有 deadline 时生效；
+// Methods 的键支持 /pkg.Service/* 形式，Default 只作用于一元调用，流式调用仅使用 Methods
+type ClientTimeouts struct {
+	Default time.Duration            `yaml:"default" json:"default"`
+	Methods map[string]time.Duration `yaml:"methods" json:"methods"`
+}
+
+func (t *ClientTimeouts) timeout(fullMethod string, stream bool) time.Duration {
+	if t == nil {
+		return 0
+	}
+	if d, ok := lookupMethod(t.Methods, fullMethod); ok {
+		return d
+	}
+	if stream {
+		return 0
+	}
+	return t.Default
+}
+
+// WithClientDefaultTimeout 调用没有 deadline 时使用的默认超时
+func WithClientDefaultTimeout(timeout time.Duration) ClientOption {
+	return func(o *ClientOptions) {
+		o.Timeouts.Default = timeout
+	}
+}
+
+// WithClientMethodTimeouts 按方法设置调用没有 deadline 时使用的超时
+func WithClientMethodTimeouts(timeouts map[string]time.Duration) ClientOption {
+	return func(o *ClientOptions) {
+		o.Timeouts.Methods = timeouts
+	}
+}
+
+// SetTimeouts 运行时替换默认超时
+func (c *GrpcClient) SetTimeouts(timeouts ClientTimeouts) {
+	c.timeouts.Store(&timeouts)
+}
+
+// Timeouts 当前默认超时
+func (c *GrpcClient) Timeouts() ClientTimeouts {
+	if t := c.timeouts.Load(); t != nil {
+		return *t
+	}
+	return ClientTimeouts{}
+}
+
+func withDefaultTimeout(ctx context.Context, timeouts *atomic.Pointer[ClientTimeouts], method string, stream bool) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok {
+		return ctx, func() {}
+	}
+	if d := timeouts.Load().timeout(method, stream); d > 0 {
+		return context.WithTimeout(ctx, d)
+	}
+	return ctx, func() {}
+}
+
+func (c *GrpcClient) timeoutUnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		ctx, cancel := withDefaultTimeout(ctx, &c.timeouts, method, false)
+		defer cancel()
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+func (c *GrpcClient) timeoutStreamClientInterceptor() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		ctx, cancel := withDefaultTimeout(ctx, &c.timeouts, method, true)
+		stream, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		// 超时上下文随流结束释放
+		context.AfterFunc(stream.Context(), cancel)
+		return stream, nil
+	}
+}
+
+// DeadlineLimit 服务端 deadline 限制
+type DeadlineLimit struct {
+	// Max 允许的最大 deadline，超过或没有 deadline 时缩短为 Max，为0时不限制
+	Max time.Duration
+	// MinRemaining 剩余时间少于该值时直接拒绝，为0时不检查
+	MinRemaining time.Duration
+}
+
+func (l DeadlineLimit) apply(ctx context.Context) (context.Context, context.CancelFunc, error) {
+	deadline, ok := ctx.Deadline()
+	if ok && l.MinRemaining > 0 {
+		if remaining := time.Until(deadline); remaining < l.MinRemaining {
+			return nil, nil, status.Errorf(codes.DeadlineExceeded, "insufficient deadline budget: %s remaining, %s required", remaining, l.MinRemaining)
+		}
+	}
+	if l.Max > 0 && (!ok || time.Until(deadline) > l.Max) {
+		ctx, cancel := context.WithTimeout(ctx, l.Max)
+		return ctx, cancel, nil
+	}
+	return ctx, func() {}, nil
+}
+
+// UnaryServerInterceptor deadline 限制一元拦截器
+func (l DeadlineLimit) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+		ctx, cancel, err := l.apply(ctx)
+		if err != nil {
+			return nil, err
+		}
+		defer cancel()
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor deadline 限制流拦截器
+func (l DeadlineLimit) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, cancel, err := l.apply(stream.Context())
+		if err != nil {
+			return err
+		}
+		defer cancel()
+		wrapped := WrapServerStream(stream)
+		wrapped.WrappedContext = ctx
+		return handler(srv, wrapped)
+	}
+}
+
+// WithServerDeadlineLimit 限制最大 deadline，拒绝剩余时间不足的请求
+func WithServerDeadlineLimit(limit DeadlineLimit) ServerOption {
+	return func(o *ServerOptions) {
+		o.DeadlineLimit = &limit
+		o.unaryInterceptors = append(o.unaryInterceptors, limit.UnaryServerInterceptor())
+		o.streamInterceptors = append(o.streamInterceptors, limit.StreamServerInterceptor())
+	}
+}
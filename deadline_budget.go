@@ -0,0 +1,140 @@
+package ngrpc
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/noop"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// DeadlineBudgetHeader 客户端发出调用时剩余的 deadline（毫秒），与 grpc-timeout 一致，用于观测每一跳消耗的时间
+const DeadlineBudgetHeader = "x-deadline-budget-ms"
+
+// MetricDeadlineExceededOnArrival 到达服务端时 deadline 已经超时的请求数
+const MetricDeadlineExceededOnArrival = "ngrpc.server.deadline_exceeded_on_arrival"
+
+// withDeadlineBudget 预留 margin 后传递剩余的 deadline，并写入预算头
+func withDeadlineBudget(ctx context.Context, margin time.Duration) (context.Context, context.CancelFunc, error) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return ctx, func() {}, nil
+	}
+	deadline = deadline.Add(-margin)
+	remaining := time.Until(deadline)
+	if remaining <= 0 {
+		return nil, nil, status.Errorf(codes.DeadlineExceeded, "deadline budget exhausted before call (margin %s)", margin)
+	}
+	ctx, cancel := context.WithDeadline(ctx, deadline)
+	ctx = metadata.AppendToOutgoingContext(ctx, DeadlineBudgetHeader, strconv.FormatInt(remaining.Milliseconds(), 10))
+	return ctx, cancel, nil
+}
+
+// DeadlineBudgetUnaryClientInterceptor 传递剩余 deadline 减去 margin
+func DeadlineBudgetUnaryClientInterceptor(margin time.Duration) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		ctx, cancel, err := withDeadlineBudget(ctx, margin)
+		if err != nil {
+			return err
+		}
+		defer cancel()
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+// DeadlineBudgetStreamClientInterceptor 传递剩余 deadline 减去 margin
+func DeadlineBudgetStreamClientInterceptor(margin time.Duration) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		ctx, cancel, err := withDeadlineBudget(ctx, margin)
+		if err != nil {
+			return nil, err
+		}
+		stream, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		context.AfterFunc(stream.Context(), cancel)
+		return stream, nil
+	}
+}
+
+// WithClientDeadlineBudget 调用下游时预留 margin 作为本服务处理响应的时间，并写入 x-deadline-budget-ms
+func WithClientDeadlineBudget(margin time.Duration) ClientOption {
+	return func(o *ClientOptions) {
+		o.unaryInterceptors = append(o.unaryInterceptors, DeadlineBudgetUnaryClientInterceptor(margin))
+		o.streamInterceptors = append(o.streamInterceptors, DeadlineBudgetStreamClientInterceptor(margin))
+	}
+}
+
+// deadlineBudget 服务端记录请求到达时的 deadline 预算
+type deadlineBudget struct {
+	log      Logger
+	exceeded metric.Int64Counter
+}
+
+// arrive 到达时已超时返回错误，否则将预算写入日志字段
+func (b *deadlineBudget) arrive(ctx context.Context, fullMethod string) (context.Context, error) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return ctx, nil
+	}
+	remaining := time.Until(deadline)
+	md, _ := metadata.FromIncomingContext(ctx)
+	sent := firstMetadata(md, DeadlineBudgetHeader)
+	if remaining <= 0 || ctx.Err() != nil {
+		b.exceeded.Add(ctx, 1, metric.WithAttributes(attribute.String("rpc.method", fullMethod)))
+		b.log.Warnf(ctx, "deadline exceeded on arrival: %s sent_budget_ms=%s", fullMethod, sent)
+		return nil, status.Error(codes.DeadlineExceeded, "deadline exceeded on arrival")
+	}
+	fields := []interface{}{"deadline_budget_ms", remaining.Milliseconds()}
+	if sent != "" {
+		fields = append(fields, "sent_budget_ms", sent)
+	}
+	return WithLogFields(ctx, fields...), nil
+}
+
+// WithServerDeadlineBudget 记录请求到达时剩余的 deadline，已超时的请求直接拒绝并计入
+// ngrpc.server.deadline_exceeded_on_arrival 指标，指标使用 WithServerMetrics 或全局 MeterProvider
+func WithServerDeadlineBudget() ServerOption {
+	return func(o *ServerOptions) {
+		// 每个服务端使用各自的日志和指标，同一个 ServerOption 可用于多个服务端
+		budget := new(deadlineBudget)
+		o.unaryInterceptors = append(o.unaryInterceptors, func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+			if ctx, err = budget.arrive(ctx, info.FullMethod); err != nil {
+				return
+			}
+			return handler(ctx, req)
+		})
+		o.streamInterceptors = append(o.streamInterceptors, func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			ctx, err := budget.arrive(stream.Context(), info.FullMethod)
+			if err != nil {
+				return err
+			}
+			wrapped := WrapServerStream(stream)
+			wrapped.WrappedContext = ctx
+			return handler(srv, wrapped)
+		})
+		o.hooks = append(o.hooks, func(s *GrpcServer) {
+			meterProvider := s.opts.MeterProvider
+			if meterProvider == nil {
+				meterProvider = otel.GetMeterProvider()
+			}
+			budget.log = s.opts.Log
+			counter, err := meterProvider.Meter("github.com/nilorg/ngrpc").Int64Counter(MetricDeadlineExceededOnArrival,
+				metric.WithDescription("Requests whose deadline had already expired when they arrived"))
+			if err != nil {
+				s.opts.Log.Errorf(s.ctx, "create metric %s: %v", MetricDeadlineExceededOnArrival, err)
+				counter, _ = noop.Meter{}.Int64Counter(MetricDeadlineExceededOnArrival)
+			}
+			budget.exceeded = counter
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package ngrpc
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// budgetRecorder 记录服务端收到的剩余时间、预算头和日志字段
+type budgetRecorder struct {
+	mu        sync.Mutex
+	remaining time.Duration
+	sent      string
+	fields    []interface{}
+}
+
+func (r *budgetRecorder) interceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	deadline, _ := ctx.Deadline()
+	md, _ := metadata.FromIncomingContext(ctx)
+	r.mu.Lock()
+	r.remaining = time.Until(deadline)
+	r.sent = firstMetadata(md, DeadlineBudgetHeader)
+	r.fields = LogFields(ctx)
+	r.mu.Unlock()
+	return handler(ctx, req)
+}
+
+// last 最近一次调用的记录
+func (r *budgetRecorder) last() (remaining time.Duration, sent string, fields []interface{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.remaining, r.sent, r.fields
+}
+
+func TestDeadlineBudgetMargin(t *testing.T) {
+	recorder := new(budgetRecorder)
+	server := startEchoServer(t, WithServerDeadlineBudget(), WithServerUnaryServerInterceptors(recorder.interceptor))
+	const margin = 500 * time.Millisecond
+	conn := dialEcho(t, server, grpc.WithChainUnaryInterceptor(DeadlineBudgetUnaryClientInterceptor(margin)))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := conn.Invoke(ctx, echoUnary, wrapperspb.String("hi"), new(wrapperspb.StringValue)); err != nil {
+		t.Fatal(err)
+	}
+	remaining, sent, fields := recorder.last()
+	if remaining > 2*time.Second-margin || remaining < time.Second {
+		t.Fatalf("server remaining %s, want about %s", remaining, 2*time.Second-margin)
+	}
+	if ms, err := strconv.ParseInt(sent, 10, 64); err != nil || ms > (2*time.Second-margin).Milliseconds() || ms < time.Second.Milliseconds() {
+		t.Fatalf("budget header %q, want about %d", sent, (2*time.Second - margin).Milliseconds())
+	}
+	logFields := map[interface{}]interface{}{}
+	for i := 0; i+1 < len(fields); i += 2 {
+		logFields[fields[i]] = fields[i+1]
+	}
+	if _, ok := logFields["deadline_budget_ms"]; !ok || logFields["sent_budget_ms"] != sent {
+		t.Fatalf("log fields %v, want deadline_budget_ms and sent_budget_ms", fields)
+	}
+
+	// margin 超过剩余时间时不发出调用
+	ctx, cancel = context.WithTimeout(context.Background(), margin/2)
+	defer cancel()
+	err := conn.Invoke(ctx, echoUnary, wrapperspb.String("hi"), new(wrapperspb.StringValue))
+	if status.Code(err) != codes.DeadlineExceeded {
+		t.Fatalf("exhausted budget: %v, want DeadlineExceeded", err)
+	}
+	// 没有 deadline 时不写入预算头
+	if err = conn.Invoke(context.Background(), echoUnary, wrapperspb.String("hi"), new(wrapperspb.StringValue)); err != nil {
+		t.Fatal(err)
+	}
+	if _, sent, _ = recorder.last(); sent != "" {
+		t.Fatalf("budget header %q without deadline", sent)
+	}
+}
+
+// exceededOnArrival 读取到达时已超时的请求数
+func exceededOnArrival(t *testing.T, reader *sdkmetric.ManualReader) int64 {
+	t.Helper()
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatal(err)
+	}
+	var total int64
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if sum, ok := m.Data.(metricdata.Sum[int64]); ok && m.Name == MetricDeadlineExceededOnArrival {
+				for _, dp := range sum.DataPoints {
+					total += dp.Value
+				}
+			}
+		}
+	}
+	return total
+}
+
+func TestDeadlineBudgetExpiredOnArrival(t *testing.T) {
+	// 同一个 ServerOption 用于两个服务端，各自计数
+	option := WithServerDeadlineBudget()
+	readers := []*sdkmetric.ManualReader{sdkmetric.NewManualReader(), sdkmetric.NewManualReader()}
+	var servers []*GrpcServer
+	for _, reader := range readers {
+		servers = append(servers, NewGrpcServer(context.Background(),
+			WithServerLogger(nopLogger{}),
+			WithServerMetrics(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))),
+			option,
+		))
+	}
+	interceptor := servers[0].opts.unaryInterceptors[0]
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) { return req, nil }
+	info := &grpc.UnaryServerInfo{FullMethod: echoUnary}
+
+	expired, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	for range 2 {
+		if _, err := interceptor(expired, nil, info, handler); status.Code(err) != codes.DeadlineExceeded {
+			t.Fatalf("expired request: %v, want DeadlineExceeded", err)
+		}
+	}
+	live, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	if _, err := interceptor(live, "ok", info, handler); err != nil {
+		t.Fatal(err)
+	}
+	if got := exceededOnArrival(t, readers[0]); got != 2 {
+		t.Fatalf("first server counted %d, want 2", got)
+	}
+	if got := exceededOnArrival(t, readers[1]); got != 0 {
+		t.Fatalf("second server counted %d, want 0", got)
+	}
+}
@@ -0,0 +1,206 @@
+package ngrpc
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// 测试用的 Echo 服务，描述符在 init 中注册，请求和响应都是 google.protobuf.StringValue
+const (
+	echoService      = "ngrpc.test.Echo"
+	echoUnary        = "/ngrpc.test.Echo/Unary"
+	echoGet          = "/ngrpc.test.Echo/Get"
+	echoServerStream = "/ngrpc.test.Echo/ServerStream"
+	echoClientStream = "/ngrpc.test.Echo/ClientStream"
+)
+
+func init() {
+	stringValue := ".google.protobuf.StringValue"
+	method := func(name string, clientStreaming, serverStreaming bool) *descriptorpb.MethodDescriptorProto {
+		return &descriptorpb.MethodDescriptorProto{
+			Name:            proto.String(name),
+			InputType:       proto.String(stringValue),
+			OutputType:      proto.String(stringValue),
+			ClientStreaming: proto.Bool(clientStreaming),
+			ServerStreaming: proto.Bool(serverStreaming),
+		}
+	}
+	get := method("Get", false, false)
+	get.Options = &descriptorpb.MethodOptions{
+		IdempotencyLevel: descriptorpb.MethodOptions_NO_SIDE_EFFECTS.Enum(),
+	}
+	file, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:       proto.String("ngrpc/test/echo.proto"),
+		Package:    proto.String("ngrpc.test"),
+		Dependency: []string{"google/protobuf/wrappers.proto"},
+		Syntax:     proto.String("proto3"),
+		Service: []*descriptorpb.ServiceDescriptorProto{{
+			Name: proto.String("Echo"),
+			Method: []*descriptorpb.MethodDescriptorProto{
+				method("Unary", false, false),
+				get,
+				method("ServerStream", false, true),
+				method("ClientStream", true, false),
+			},
+		}},
+	}, protoregistry.GlobalFiles)
+	if err != nil {
+		panic(err)
+	}
+	if err = protoregistry.GlobalFiles.RegisterFile(file); err != nil {
+		panic(err)
+	}
+}
+
+// echoServer 返回请求内容，以 error: 开头的请求返回 InvalidArgument
+type echoServer struct{}
+
+func (echoServer) reply(in *wrapperspb.StringValue) (*wrapperspb.StringValue, error) {
+	if msg, ok := strings.CutPrefix(in.GetValue(), "error:"); ok {
+		return nil, status.Error(codes.InvalidArgument, msg)
+	}
+	return wrapperspb.String(in.GetValue()), nil
+}
+
+func echoUnaryHandler(fullMethod string) grpc.MethodHandler {
+	return func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+		in := new(wrapperspb.StringValue)
+		if err := dec(in); err != nil {
+			return nil, err
+		}
+		handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+			return srv.(echoServer).reply(req.(*wrapperspb.StringValue))
+		}
+		if interceptor == nil {
+			return handler(ctx, in)
+		}
+		return interceptor(ctx, in, &grpc.UnaryServerInfo{Server: srv, FullMethod: fullMethod}, handler)
+	}
+}
+
+// echoServerStreamHandler 按字符逐条返回
+func echoServerStreamHandler(srv interface{}, stream grpc.ServerStream) error {
+	in := new(wrapperspb.StringValue)
+	if err := stream.RecvMsg(in); err != nil {
+		return err
+	}
+	for _, r := range in.GetValue() {
+		if err := stream.SendMsg(wrapperspb.String(string(r))); err != nil {
+			return err
+		}
+	}
+	if msg, ok := strings.CutPrefix(in.GetValue(), "error:"); ok {
+		return status.Error(codes.InvalidArgument, msg)
+	}
+	return nil
+}
+
+// echoClientStreamHandler 返回全部请求拼接的内容
+func echoClientStreamHandler(srv interface{}, stream grpc.ServerStream) error {
+	var sb strings.Builder
+	for {
+		in := new(wrapperspb.StringValue)
+		err := stream.RecvMsg(in)
+		if err == io.EOF {
+			return stream.SendMsg(wrapperspb.String(sb.String()))
+		}
+		if err != nil {
+			return err
+		}
+		sb.WriteString(in.GetValue())
+	}
+}
+
+var echoServiceDesc = grpc.ServiceDesc{
+	ServiceName: echoService,
+	HandlerType: (*interface{})(nil),
+	Methods: []grpc.MethodDesc{
+		{MethodName: "Unary", Handler: echoUnaryHandler(echoUnary)},
+		{MethodName: "Get", Handler: echoUnaryHandler(echoGet)},
+	},
+	Streams: []grpc.StreamDesc{
+		{StreamName: "ServerStream", Handler: echoServerStreamHandler, ServerStreams: true},
+		{StreamName: "ClientStream", Handler: echoClientStreamHandler, ClientStreams: true},
+	},
+	Metadata: "ngrpc/test/echo.proto",
+}
+
+// startEchoServer 启动注册了 Echo 服务的服务端，测试结束时停止
+func startEchoServer(t *testing.T, opts ...ServerOption) *GrpcServer {
+	t.Helper()
+	opts = append([]ServerOption{WithServerAddress("127.0.0.1:0"), WithServerLogger(nopLogger{})}, opts...)
+	server := NewGrpcServer(context.Background(), opts...)
+	server.GetSrv().RegisterService(&echoServiceDesc, echoServer{})
+	if err := server.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(server.Stop)
+	return server
+}
+
+// dialEcho 连接服务端，测试结束时关闭
+func dialEcho(t *testing.T, server *GrpcServer, opts ...grpc.DialOption) *grpc.ClientConn {
+	t.Helper()
+	opts = append([]grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}, opts...)
+	conn, err := grpc.NewClient(server.Addr().String(), opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// nopLogger 丢弃全部日志
+type nopLogger struct{}
+
+func (nopLogger) Debugf(ctx context.Context, format string, args ...interface{}) {}
+func (nopLogger) Debugln(ctx context.Context, args ...interface{})               {}
+func (nopLogger) Infof(ctx context.Context, format string, args ...interface{})  {}
+func (nopLogger) Infoln(ctx context.Context, args ...interface{})                {}
+func (nopLogger) Warnf(ctx context.Context, format string, args ...interface{})  {}
+func (nopLogger) Warnln(ctx context.Context, args ...interface{})                {}
+func (nopLogger) Errorf(ctx context.Context, format string, args ...interface{}) {}
+func (nopLogger) Errorln(ctx context.Context, args ...interface{})               {}
+func (nopLogger) Fatalf(ctx context.Context, format string, args ...interface{}) {}
+func (nopLogger) Fatalln(ctx context.Context, args ...interface{})               {}
+
+// recordLogger 记录 Warn 及以上级别的日志
+type recordLogger struct {
+	nopLogger
+	mu    sync.Mutex
+	lines []string
+}
+
+func (l *recordLogger) add(level, msg string) {
+	l.mu.Lock()
+	l.lines = append(l.lines, level+" "+msg)
+	l.mu.Unlock()
+}
+
+func (l *recordLogger) Warnf(ctx context.Context, format string, args ...interface{}) {
+	l.add("warn", fmt.Sprintf(format, args...))
+}
+
+func (l *recordLogger) Errorf(ctx context.Context, format string, args ...interface{}) {
+	l.add("error", fmt.Sprintf(format, args...))
+}
+
+func (l *recordLogger) Lines() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]string(nil), l.lines...)
+}
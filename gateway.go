@@ -0,0 +1,147 @@
+package ngrpc
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+// gatewayBufferSize 进程内连接的缓冲区大小
+const gatewayBufferSize = 1 << 20
+
+// GatewayRegisterFunc 注册 grpc-gateway handler，与生成的 RegisterXxxHandler 函数签名一致
+type GatewayRegisterFunc func(ctx context.Context, mux *runtime.ServeMux, conn *grpc.ClientConn) error
+
+// WithServerGateway 在 address 上提供 grpc-gateway REST/JSON 服务，通过进程内连接调用本服务
+func WithServerGateway(address string, registers ...GatewayRegisterFunc) ServerOption {
+	return func(o *ServerOptions) {
+		o.GatewayAddress = address
+		o.gatewayRegisters = append(o.gatewayRegisters, registers...)
+	}
+}
+
+// WithServerGatewayMuxOptions grpc-gateway ServeMux 参数
+func WithServerGatewayMuxOptions(opts ...runtime.ServeMuxOption) ServerOption {
+	return func(o *ServerOptions) {
+		o.gatewayMuxOptions = append(o.gatewayMuxOptions, opts...)
+	}
+}
+
+// WithServerGatewayErrorHandler 自定义错误响应，默认按 gRPC 状态码映射 HTTP 状态码并输出 google.rpc.Status
+func WithServerGatewayErrorHandler(handler runtime.ErrorHandlerFunc) ServerOption {
+	return WithServerGatewayMuxOptions(runtime.WithErrorHandler(handler))
+}
+
+// gatewayListeners 创建进程内 gRPC 监听和 gateway HTTP 监听
+func (s *GrpcServer) gatewayListeners() (listeners []*serverListener, err error) {
+	if s.opts.GatewayAddress == "" {
+		return
+	}
+	inner := newPipeListener(gatewayBufferSize)
+	conn, err := grpc.NewClient("passthrough:///ngrpc-gateway",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return inner.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		inner.Close()
+		return nil, fmt.Errorf("gateway dial: %w", err)
+	}
+	mux := runtime.NewServeMux(s.opts.gatewayMuxOptions...)
+	for _, register := range s.opts.gatewayRegisters {
+		if err = register(s.ctx, mux, conn); err != nil {
+			conn.Close()
+			inner.Close()
+			return nil, fmt.Errorf("gateway register: %w", err)
+		}
+	}
+	lis, err := Listen(s.opts.GatewayAddress, s.opts.SocketMode)
+	if err != nil {
+		conn.Close()
+		inner.Close()
+		return nil, fmt.Errorf("listen gateway %s: %w", s.opts.GatewayAddress, err)
+	}
+	s.mu.Lock()
+	s.gatewayConn = conn
+	s.mu.Unlock()
+	listeners = append(listeners,
+		&serverListener{
+			// 进程内连接不加密，服务端配置了凭证时按监听选择凭证
+			Listener: &credsListener{Listener: inner, creds: insecure.NewCredentials()},
+			config:   ServerListener{NoRegister: true},
+			internal: true,
+		},
+		&serverListener{
+			Listener: lis,
+			config:   ServerListener{Address: s.opts.GatewayAddress, NoRegister: true},
+			handler:  mux,
+		},
+	)
+	return
+}
+
+// closeGateway 关闭进程内连接
+func (s *GrpcServer) closeGateway() {
+	s.mu.Lock()
+	conn := s.gatewayConn
+	s.gatewayConn = nil
+	s.mu.Unlock()
+	if conn != nil {
+		conn.Close()
+	}
+}
+
+// gatewayPeer 经 gateway 进程内连接的调用使用 gateway 追加在 x-forwarded-for 末尾的 HTTP 客户端地址作为对端，
+// 使按IP限流、日志等拦截器看到真实地址；x-forwarded-for 中其余的值由客户端提供，不可信
+func gatewayPeer(ctx context.Context) context.Context {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return ctx
+	}
+	if _, ok = p.Addr.(pipeAddr); !ok {
+		return ctx
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get("x-forwarded-for")
+	if len(values) == 0 {
+		return ctx
+	}
+	forwarded := values[len(values)-1]
+	if i := strings.LastIndexByte(forwarded, ','); i >= 0 {
+		forwarded = forwarded[i+1:]
+	}
+	ip := net.ParseIP(strings.TrimSpace(forwarded))
+	if ip == nil {
+		return ctx
+	}
+	return peer.NewContext(ctx, &peer.Peer{
+		Addr:      &net.TCPAddr{IP: ip},
+		LocalAddr: p.LocalAddr,
+		AuthInfo:  p.AuthInfo,
+	})
+}
+
+func gatewayPeerUnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		return handler(gatewayPeer(ctx), req)
+	}
+}
+
+func gatewayPeerStreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if ctx := gatewayPeer(ss.Context()); ctx != ss.Context() {
+			wrapped := WrapServerStream(ss)
+			wrapped.WrappedContext = ctx
+			ss = wrapped
+		}
+		return handler(srv, ss)
+	}
+}
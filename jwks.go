@@ -0,0 +1,249 @@
+package ngrpc
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	// jwksMinRefreshInterval 两次刷新尝试的最小间隔，用于未知kid和刷新失败后的重试
+	jwksMinRefreshInterval = 10 * time.Second
+	// jwksFetchTimeout Keyfunc 触发刷新时的超时
+	jwksFetchTimeout = 5 * time.Second
+)
+
+// JWKS JSON Web Key Set，source 可以是本地文件路径或 http(s) 地址，按 refresh 间隔刷新
+type JWKS struct {
+	source  string
+	refresh time.Duration
+	client  *http.Client
+
+	mu      sync.RWMutex
+	keys    map[string]interface{}
+	fetched time.Time
+	// attempted 最近一次刷新的时间，无论成功与否
+	attempted time.Time
+	// refreshing 进行中的刷新，并发的刷新等待同一结果
+	refreshing *jwksRefresh
+}
+
+type jwksRefresh struct {
+	done chan struct{}
+	err  error
+}
+
+// NewJWKS 创建并加载JWKS，refresh 为0时不定期刷新
+func NewJWKS(ctx context.Context, source string, refresh time.Duration) (*JWKS, error) {
+	j := &JWKS{
+		source:  source,
+		refresh: refresh,
+		client:  &http.Client{Timeout: 10 * time.Second},
+	}
+	if err := j.Refresh(ctx); err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+// Refresh 重新加载JWKS，已有刷新进行中时等待其结果
+func (j *JWKS) Refresh(ctx context.Context) error {
+	j.mu.Lock()
+	if call := j.refreshing; call != nil {
+		j.mu.Unlock()
+		select {
+		case <-call.done:
+			return call.err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	call := &jwksRefresh{done: make(chan struct{})}
+	j.refreshing = call
+	j.mu.Unlock()
+
+	call.err = j.fetch(ctx)
+	j.mu.Lock()
+	j.refreshing = nil
+	j.mu.Unlock()
+	close(call.done)
+	return call.err
+}
+
+func (j *JWKS) fetch(ctx context.Context) error {
+	data, err := j.read(ctx)
+	var keys map[string]interface{}
+	if err == nil {
+		keys, err = parseJWKS(data)
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.attempted = time.Now()
+	if err != nil {
+		return fmt.Errorf("jwks %s: %w", j.source, err)
+	}
+	j.keys = keys
+	j.fetched = j.attempted
+	return nil
+}
+
+func (j *JWKS) read(ctx context.Context) ([]byte, error) {
+	if !strings.HasPrefix(j.source, "http://") && !strings.HasPrefix(j.source, "https://") {
+		return os.ReadFile(j.source)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, j.source, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := j.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+}
+
+func (j *JWKS) lookup(kid string) (key interface{}, ok, refresh bool) {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+	if kid == "" && len(j.keys) == 1 {
+		for _, key = range j.keys {
+			ok = true
+		}
+	} else {
+		key, ok = j.keys[kid]
+	}
+	stale := j.refresh > 0 && time.Since(j.fetched) > j.refresh
+	refresh = (stale || !ok) && time.Since(j.attempted) > jwksMinRefreshInterval
+	return
+}
+
+// Keyfunc 用于 jwt 解析的 Keyfunc，缓存过期或 kid 未知时刷新，
+// 刷新失败后至少间隔 jwksMinRefreshInterval 才重试，期间继续使用已有的密钥
+func (j *JWKS) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, ok, refresh := j.lookup(kid)
+	if refresh {
+		ctx, cancel := context.WithTimeout(context.Background(), jwksFetchTimeout)
+		err := j.Refresh(ctx)
+		cancel()
+		if err == nil {
+			key, ok, _ = j.lookup(kid)
+		} else if !ok {
+			return nil, err
+		}
+	}
+	if !ok {
+		return nil, fmt.Errorf("jwks: unknown kid %q", kid)
+	}
+	return key, nil
+}
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func parseJWKS(data []byte) (map[string]interface{}, error) {
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, err
+	}
+	keys := make(map[string]interface{}, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.publicKey()
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", k.Kid, err)
+		}
+		if key != nil {
+			keys[k.Kid] = key
+		}
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("no signing keys")
+	}
+	return keys, nil
+}
+
+func (k jsonWebKey) publicKey() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBase64URL(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBase64URL(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeBase64URL(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBase64URL(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeBase64URL(k.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key size")
+		}
+		return ed25519.PublicKey(x), nil
+	}
+	// 忽略不支持的密钥类型
+	return nil, nil
+}
+
+func decodeBase64URL(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+}
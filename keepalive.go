@@ -0,0 +1,170 @@
+package ngrpc
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/keepalive"
+)
+
+var (
+	// DefaultClientKeepalive 客户端默认 keepalive 参数
+	DefaultClientKeepalive = keepalive.ClientParameters{
+		Time:                2 * time.Minute,  // 每2分钟发送一次 ping
+		Timeout:             10 * time.Second, // 超过10秒无响应就断开
+		PermitWithoutStream: true,             // 即使没有 RPC 也发送 ping
+	}
+	// DefaultServerKeepalivePolicy 服务端默认 keepalive 约束，允许 DefaultClientKeepalive 的 ping 频率，
+	// grpc-go 默认约束为5分钟且不允许无 RPC 时 ping，会向默认客户端发送 GOAWAY "too_many_pings"
+	DefaultServerKeepalivePolicy = keepalive.EnforcementPolicy{
+		MinTime:             time.Minute,
+		PermitWithoutStream: true,
+	}
+)
+
+// WithServerKeepalive 服务端 keepalive 参数，包括空闲连接、连接最长存活时间和服务端 ping；
+// 只覆盖不为零的字段，与 WithServerMaxConnectionAge 等参数合并
+func WithServerKeepalive(params keepalive.ServerParameters) ServerOption {
+	return func(o *ServerOptions) {
+		if params.MaxConnectionIdle != 0 {
+			o.Keepalive.MaxConnectionIdle = params.MaxConnectionIdle
+		}
+		if params.MaxConnectionAge != 0 {
+			o.Keepalive.MaxConnectionAge = params.MaxConnectionAge
+		}
+		if params.MaxConnectionAgeGrace != 0 {
+			o.Keepalive.MaxConnectionAgeGrace = params.MaxConnectionAgeGrace
+		}
+		if params.Time != 0 {
+			o.Keepalive.Time = params.Time
+		}
+		if params.Timeout != 0 {
+			o.Keepalive.Timeout = params.Timeout
+		}
+	}
+}
+
+// WithServerKeepalivePolicy 服务端对客户端 ping 的约束，默认为 DefaultServerKeepalivePolicy
+func WithServerKeepalivePolicy(policy keepalive.EnforcementPolicy) ServerOption {
+	return func(o *ServerOptions) {
+		o.KeepalivePolicy = policy
+	}
+}
+
+// WithServerClientKeepalive 同一配置下客户端的 keepalive 参数，设置后 Run 时校验是否符合服务端约束，不符合时返回错误
+func WithServerClientKeepalive(params keepalive.ClientParameters) ServerOption {
+	return func(o *ServerOptions) {
+		o.ClientKeepalive = &params
+	}
+}
+
+// WithServerMaxConnectionAge 连接最长存活时间，到期后发送 GOAWAY 并在 grace 后强制关闭，便于负载均衡重新分配连接
+func WithServerMaxConnectionAge(age, grace time.Duration) ServerOption {
+	return func(o *ServerOptions) {
+		o.Keepalive.MaxConnectionAge = age
+		o.Keepalive.MaxConnectionAgeGrace = grace
+	}
+}
+
+// WithServerMaxRecvMsgSize 服务端接收消息的最大字节数，默认4MB
+func WithServerMaxRecvMsgSize(size int) ServerOption {
+	return func(o *ServerOptions) {
+		o.MaxRecvMsgSize = size
+	}
+}
+
+// WithServerMaxSendMsgSize 服务端发送消息的最大字节数，默认不限制
+func WithServerMaxSendMsgSize(size int) ServerOption {
+	return func(o *ServerOptions) {
+		o.MaxSendMsgSize = size
+	}
+}
+
+// WithServerMaxConcurrentStreams 每个连接的最大并发流数
+func WithServerMaxConcurrentStreams(n uint32) ServerOption {
+	return func(o *ServerOptions) {
+		o.MaxConcurrentStreams = n
+	}
+}
+
+// WithClientKeepalive 客户端 keepalive 参数，默认为 DefaultClientKeepalive
+func WithClientKeepalive(params keepalive.ClientParameters) ClientOption {
+	return func(o *ClientOptions) {
+		o.Keepalive = params
+	}
+}
+
+// WithClientKeepalivePolicy 同一配置下服务端的 keepalive 约束，设置后创建客户端时校验 keepalive 参数，不符合时按 Fatal 处理
+func WithClientKeepalivePolicy(policy keepalive.EnforcementPolicy) ClientOption {
+	return func(o *ClientOptions) {
+		o.KeepalivePolicy = &policy
+	}
+}
+
+// ValidateKeepalive 校验客户端 keepalive 参数是否符合服务端约束，
+// 不符合时服务端会以 GOAWAY "too_many_pings" 关闭连接
+func ValidateKeepalive(client keepalive.ClientParameters, policy keepalive.EnforcementPolicy) error {
+	var errs []error
+	if client.Time > 0 && client.Time < policy.MinTime {
+		errs = append(errs, fmt.Errorf("client keepalive time %s is less than server enforcement min time %s", client.Time, policy.MinTime))
+	}
+	if client.PermitWithoutStream && !policy.PermitWithoutStream {
+		errs = append(errs, errors.New("client sends pings without active streams but server enforcement does not permit it"))
+	}
+	return errors.Join(errs...)
+}
+
+// validateTransport 校验服务端连接参数
+func (o *ServerOptions) validateTransport() error {
+	var errs []error
+	if o.MaxRecvMsgSize < 0 || o.MaxSendMsgSize < 0 {
+		errs = append(errs, errors.New("max message size must not be negative"))
+	}
+	if o.Keepalive.MaxConnectionAgeGrace > 0 && o.Keepalive.MaxConnectionAge == 0 {
+		errs = append(errs, errors.New("max connection age grace is set without max connection age"))
+	}
+	if o.Keepalive.Timeout < 0 || o.Keepalive.Time < 0 || o.KeepalivePolicy.MinTime < 0 {
+		errs = append(errs, errors.New("keepalive durations must not be negative"))
+	}
+	if (o.HTTPHandler != nil || o.Web != nil) && o.http2TransportSet() {
+		errs = append(errs, errors.New("keepalive, max connection age and max concurrent streams are not supported with HTTP handler or web, connections are handled by net/http"))
+	}
+	if o.Web != nil {
+		if err := o.Web.validate(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if o.ClientKeepalive != nil {
+		if err := ValidateKeepalive(*o.ClientKeepalive, o.KeepalivePolicy); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// http2TransportSet 是否设置了只对 gRPC 自身的 HTTP/2 传输生效的参数
+func (o *ServerOptions) http2TransportSet() bool {
+	return o.Keepalive != (keepalive.ServerParameters{}) ||
+		o.KeepalivePolicy != DefaultServerKeepalivePolicy ||
+		o.MaxConcurrentStreams > 0
+}
+
+// transportServerOptions 连接相关的 grpc 服务端参数
+func (o *ServerOptions) transportServerOptions() []grpc.ServerOption {
+	opts := []grpc.ServerOption{
+		grpc.KeepaliveParams(o.Keepalive),
+		grpc.KeepaliveEnforcementPolicy(o.KeepalivePolicy),
+	}
+	if o.MaxRecvMsgSize > 0 {
+		opts = append(opts, grpc.MaxRecvMsgSize(o.MaxRecvMsgSize))
+	}
+	if o.MaxSendMsgSize > 0 {
+		opts = append(opts, grpc.MaxSendMsgSize(o.MaxSendMsgSize))
+	}
+	if o.MaxConcurrentStreams > 0 {
+		opts = append(opts, grpc.MaxConcurrentStreams(o.MaxConcurrentStreams))
+	}
+	return opts
+}
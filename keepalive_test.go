@@ -0,0 +1,43 @@
+package ngrpc
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/keepalive"
+)
+
+func TestValidateTransport(t *testing.T) {
+	web := WithServerWeb(WebOptions{})
+	mux := WithServerHTTPHandler(http.NotFoundHandler())
+	tests := []struct {
+		name    string
+		opts    []ServerOption
+		wantErr bool
+	}{
+		{name: "default"},
+		{name: "grpc keepalive", opts: []ServerOption{
+			WithServerKeepalive(keepalive.ServerParameters{MaxConnectionIdle: time.Minute}),
+			WithServerMaxConnectionAge(time.Hour, time.Minute),
+			WithServerMaxConcurrentStreams(100),
+		}},
+		{name: "web default", opts: []ServerOption{web}},
+		{name: "web message size", opts: []ServerOption{web, WithServerMaxRecvMsgSize(1 << 20)}},
+		{name: "web keepalive", opts: []ServerOption{web, WithServerKeepalive(keepalive.ServerParameters{Time: time.Minute})}, wantErr: true},
+		{name: "handler policy", opts: []ServerOption{mux, WithServerKeepalivePolicy(keepalive.EnforcementPolicy{MinTime: time.Second})}, wantErr: true},
+		{name: "handler max connection age", opts: []ServerOption{mux, WithServerMaxConnectionAge(time.Hour, 0)}, wantErr: true},
+		{name: "handler max concurrent streams", opts: []ServerOption{mux, WithServerMaxConcurrentStreams(100)}, wantErr: true},
+		{name: "grace without age", opts: []ServerOption{WithServerKeepalive(keepalive.ServerParameters{MaxConnectionAgeGrace: time.Minute})}, wantErr: true},
+		{name: "negative message size", opts: []ServerOption{WithServerMaxSendMsgSize(-1)}, wantErr: true},
+		{name: "incompatible client", opts: []ServerOption{WithServerClientKeepalive(keepalive.ClientParameters{Time: time.Second})}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := NewServerOptions(tt.opts...)
+			if err := opts.validateTransport(); (err != nil) != tt.wantErr {
+				t.Fatalf("validateTransport() = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,284 @@
+package ngrpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/credentials"
+)
+
+const (
+	// unixScheme Unix domain socket 地址前缀，如 unix:///var/run/app.sock
+	unixScheme = "unix:"
+	// systemdScheme systemd socket activation 地址前缀，systemd: 为第一个socket，systemd:name 为 FileDescriptorName 对应的socket
+	systemdScheme = "systemd:"
+	// sdListenFdsStart systemd 传递的第一个文件描述符
+	sdListenFdsStart = 3
+)
+
+// WithServerListener 使用已打开的 listener，忽略 Address 和 RandomPort
+func WithServerListener(lis net.Listener) ServerOption {
+	return func(o *ServerOptions) {
+		o.Listener = lis
+	}
+}
+
+// WithServerSocketMode 设置 Unix domain socket 文件权限
+func WithServerSocketMode(mode os.FileMode) ServerOption {
+	return func(o *ServerOptions) {
+		o.SocketMode = mode
+	}
+}
+
+// Listen 按地址创建 listener，支持 host:port、unix:///path.sock 和 systemd:[name]
+func Listen(address string, socketMode os.FileMode) (net.Listener, error) {
+	switch {
+	case strings.HasPrefix(address, unixScheme):
+		return listenUnix(unixSocketPath(address), socketMode)
+	case strings.HasPrefix(address, systemdScheme):
+		return listenSystemd(strings.TrimPrefix(address, systemdScheme))
+	}
+	return net.Listen("tcp", address)
+}
+
+func unixSocketPath(address string) string {
+	path := strings.TrimPrefix(address, unixScheme)
+	if strings.HasPrefix(path, "//") {
+		path = strings.TrimPrefix(path, "//")
+	}
+	return path
+}
+
+func listenUnix(path string, mode os.FileMode) (net.Listener, error) {
+	if err := removeStaleSocket(path); err != nil {
+		return nil, err
+	}
+	lis, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if mode != 0 {
+		if err = os.Chmod(path, mode); err != nil {
+			lis.Close()
+			return nil, err
+		}
+	}
+	return lis, nil
+}
+
+// removeStaleSocket 删除上次进程遗留的 socket 文件，socket 仍在使用时返回错误
+func removeStaleSocket(path string) error {
+	fi, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if fi.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("%s exists and is not a socket", path)
+	}
+	conn, err := net.DialTimeout("unix", path, time.Second)
+	if err == nil {
+		conn.Close()
+		return fmt.Errorf("%s is in use by another process", path)
+	}
+	return os.Remove(path)
+}
+
+// listenSystemd 从 systemd socket activation 传递的文件描述符创建 listener
+func listenSystemd(name string) (net.Listener, error) {
+	if pid, err := strconv.Atoi(os.Getenv("LISTEN_PID")); err != nil || pid != os.Getpid() {
+		return nil, errors.New("systemd socket activation: LISTEN_PID is not set for this process")
+	}
+	n, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || n <= 0 {
+		return nil, errors.New("systemd socket activation: LISTEN_FDS is not set")
+	}
+	names := strings.Split(os.Getenv("LISTEN_FDNAMES"), ":")
+	for i := 0; i < n; i++ {
+		if name != "" && (i >= len(names) || names[i] != name) {
+			continue
+		}
+		fd := sdListenFdsStart + i
+		file := os.NewFile(uintptr(fd), fmt.Sprintf("systemd-listen-fd-%d", fd))
+		lis, err := net.FileListener(file)
+		file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("systemd socket activation: fd %d: %w", fd, err)
+		}
+		return lis, nil
+	}
+	return nil, fmt.Errorf("systemd socket activation: no socket named %q", name)
+}
+
+// ServerListener 服务端监听配置
+type ServerListener struct {
+	// Address 监听地址，支持 host:port、unix:///path.sock 和 systemd:[name]
+	Address string
+	// Listener 已打开的 listener，优先于 Address
+	Listener net.Listener
+	// Credentials 该监听使用的传输层凭证，为nil时使用 ServerOptions.Credentials
+	Credentials credentials.TransportCredentials
+	// Advertise 注册到注册中心的地址，为空时自动获取
+	Advertise string
+	// NoRegister 为 true 时不注册到注册中心；unix 和 systemd 监听只对本机可用，未设置 Advertise 时也不注册
+	NoRegister bool
+}
+
+// WithServerListeners 在主监听地址之外同时监听多个地址
+func WithServerListeners(listeners ...ServerListener) ServerOption {
+	return func(o *ServerOptions) {
+		o.Listeners = append(o.Listeners, listeners...)
+	}
+}
+
+// serverListener 已打开的监听
+type serverListener struct {
+	net.Listener
+	config    ServerListener
+	advertise string
+	// internal 进程内监听，始终直接提供 gRPC 服务
+	internal bool
+	// handler 不为nil时在该监听上提供 HTTP 服务
+	handler http.Handler
+}
+
+// listenerConfigs 主监听地址和额外的监听
+func (s *GrpcServer) listenerConfigs() []ServerListener {
+	primary := ServerListener{
+		Address:  s.opts.Address,
+		Listener: s.opts.Listener,
+	}
+	if s.opts.RandomPort {
+		primary.Address = ":0"
+	}
+	return append([]ServerListener{primary}, s.opts.Listeners...)
+}
+
+// listen 打开全部监听，任一失败时关闭已打开的监听
+func (s *GrpcServer) listen() (listeners []*serverListener, err error) {
+	defer func() {
+		if err != nil {
+			for _, lis := range listeners {
+				lis.Close()
+			}
+			listeners = nil
+		}
+	}()
+	for i, config := range s.listenerConfigs() {
+		if config.Credentials != nil && (s.opts.HTTPHandler != nil || s.opts.Web != nil) {
+			// HTTP 复用模式下由 net/http 处理连接，凭证不会生效
+			return listeners, fmt.Errorf("listen %s: listener credentials are not supported with HTTP handler or web, use WithServerHTTPTLSConfig", config.Address)
+		}
+		lis := config.Listener
+		if lis == nil {
+			if lis, err = Listen(config.Address, s.opts.SocketMode); err != nil {
+				return listeners, fmt.Errorf("listen %s: %w", config.Address, err)
+			}
+		}
+		if config.Credentials != nil {
+			lis = &credsListener{Listener: lis, creds: config.Credentials}
+		}
+		l := &serverListener{Listener: lis, config: config, advertise: config.Advertise}
+		listeners = append(listeners, l)
+		if l.advertise == "" {
+			fixed := config.Listener == nil && !strings.HasPrefix(config.Address, systemdScheme) && !(i == 0 && s.opts.RandomPort) &&
+				!strings.HasSuffix(config.Address, ":0")
+			if l.advertise, err = advertiseAddress(lis, config.Address, fixed); err != nil {
+				return
+			}
+		}
+	}
+	return
+}
+
+// registrable 是否注册到注册中心，本机 socket 只有显式设置 Advertise 时注册
+func (l *serverListener) registrable() bool {
+	if l.config.NoRegister {
+		return false
+	}
+	if l.config.Advertise != "" {
+		return true
+	}
+	if strings.HasPrefix(l.config.Address, unixScheme) || strings.HasPrefix(l.config.Address, systemdScheme) {
+		return false
+	}
+	_, unix := l.Addr().(*net.UnixAddr)
+	return !unix
+}
+
+// advertiseAddress 注册到注册中心的地址，fixed 为 true 时 TCP 地址使用配置的地址
+func advertiseAddress(lis net.Listener, address string, fixed bool) (string, error) {
+	switch addr := lis.Addr().(type) {
+	case *net.UnixAddr:
+		return unixScheme + "//" + addr.Name, nil
+	case *net.TCPAddr:
+		if fixed {
+			return address, nil
+		}
+		if addr.IP != nil && !addr.IP.IsUnspecified() {
+			return addr.String(), nil
+		}
+		ipAddr, err := LocalIPv4()
+		if err != nil {
+			return "", fmt.Errorf("LocalIPv4: %w", err)
+		}
+		return net.JoinHostPort(ipAddr, strconv.Itoa(addr.Port)), nil
+	}
+	return lis.Addr().String(), nil
+}
+
+// credsListener 为连接附加该监听的传输层凭证
+type credsListener struct {
+	net.Listener
+	creds credentials.TransportCredentials
+}
+
+func (l *credsListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return &credsConn{Conn: conn, creds: l.creds}, nil
+}
+
+type credsConn struct {
+	net.Conn
+	creds credentials.TransportCredentials
+}
+
+// listenerCredentials 按连接所属监听选择传输层凭证，未指定时使用默认凭证
+type listenerCredentials struct {
+	defaultCreds credentials.TransportCredentials
+}
+
+func (c *listenerCredentials) ClientHandshake(ctx context.Context, authority string, conn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	return nil, nil, errors.New("ngrpc: listener credentials are server-side only")
+}
+
+func (c *listenerCredentials) ServerHandshake(conn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	if cc, ok := conn.(*credsConn); ok {
+		return cc.creds.ServerHandshake(cc.Conn)
+	}
+	return c.defaultCreds.ServerHandshake(conn)
+}
+
+func (c *listenerCredentials) Info() credentials.ProtocolInfo {
+	return c.defaultCreds.Info()
+}
+
+func (c *listenerCredentials) Clone() credentials.TransportCredentials {
+	return &listenerCredentials{defaultCreds: c.defaultCreds.Clone()}
+}
+
+func (c *listenerCredentials) OverrideServerName(serverName string) error {
+	return nil
+}
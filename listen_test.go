@@ -0,0 +1,73 @@
+package ngrpc
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/nilorg/ngrpc/v2/resolver"
+)
+
+// recordRegistry 记录注册的地址
+type recordRegistry struct {
+	mu        sync.Mutex
+	addresses []string
+}
+
+func (r *recordRegistry) Register(serviceInfo *resolver.ServiceInfo) error {
+	r.mu.Lock()
+	r.addresses = append(r.addresses, serviceInfo.Address)
+	r.mu.Unlock()
+	return nil
+}
+
+func (r *recordRegistry) Close() error { return nil }
+
+func (r *recordRegistry) Addresses() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.addresses...)
+}
+
+func TestRegisterListeners(t *testing.T) {
+	dir := t.TempDir()
+	registry := new(recordRegistry)
+	server := NewGrpcServer(context.Background(),
+		WithServerAddress("127.0.0.1:0"),
+		WithServerLogger(nopLogger{}),
+		WithServerRegister(registry),
+		WithServerListeners(
+			ServerListener{Address: "127.0.0.1:0"},
+			ServerListener{Address: "127.0.0.1:0", NoRegister: true},
+			ServerListener{Address: unixScheme + "//" + filepath.Join(dir, "local.sock")},
+			ServerListener{Address: unixScheme + "//" + filepath.Join(dir, "shared.sock"), Advertise: "10.0.0.1:9000"},
+		),
+	)
+	if err := server.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(server.Stop)
+
+	got := registry.Addresses()
+	if len(got) != 3 {
+		t.Fatalf("registered %q, want primary, second TCP listener and advertised socket", got)
+	}
+	if got[0] != server.Addr().String() {
+		t.Fatalf("primary registered as %q, want %q", got[0], server.Addr())
+	}
+	if got[1] == got[0] || got[1] == "127.0.0.1:0" {
+		t.Fatalf("second listener registered as %q", got[1])
+	}
+	if got[2] != "10.0.0.1:9000" {
+		t.Fatalf("advertised socket registered as %q", got[2])
+	}
+	var registered []string
+	for _, serviceInfo := range server.DebugInfo().Registry.Addresses {
+		registered = append(registered, serviceInfo)
+	}
+	if !reflect.DeepEqual(registered, got) {
+		t.Fatalf("debug registry %q, want %q", registered, got)
+	}
+}
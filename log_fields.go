@@ -0,0 +1,85 @@
+package ngrpc
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/peer"
+)
+
+const (
+	// LogFieldTraceID 链路ID
+	LogFieldTraceID = "trace_id"
+	// LogFieldRequestID 请求ID
+	LogFieldRequestID = "request_id"
+	// LogFieldMethod grpc方法
+	LogFieldMethod = "method"
+	// LogFieldPeer 对端地址
+	LogFieldPeer = "peer"
+)
+
+type logFieldsKey struct{}
+
+type requestIDKey struct{}
+
+// WithLogFields 在上下文中追加日志字段，keysAndValues 依次为 key、value
+func WithLogFields(ctx context.Context, keysAndValues ...interface{}) context.Context {
+	if len(keysAndValues)%2 != 0 {
+		keysAndValues = append(keysAndValues, "")
+	}
+	fields := append(logFieldsFromContext(ctx), keysAndValues...)
+	return context.WithValue(ctx, logFieldsKey{}, fields)
+}
+
+func logFieldsFromContext(ctx context.Context) []interface{} {
+	fields, _ := ctx.Value(logFieldsKey{}).([]interface{})
+	// 复制一份，避免并发追加时共享底层数组
+	return append([]interface{}(nil), fields...)
+}
+
+// ContextWithRequestID 将请求ID放入上下文
+func ContextWithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestIDFromContext 从上下文获取请求ID
+func RequestIDFromContext(ctx context.Context) (requestID string, ok bool) {
+	requestID, ok = ctx.Value(requestIDKey{}).(string)
+	return
+}
+
+// LogFields 获取上下文中的日志字段，包括链路ID、请求ID、方法、对端地址以及 WithLogFields 添加的字段
+func LogFields(ctx context.Context) (fields []interface{}) {
+	if ctx == nil {
+		return
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.HasTraceID() {
+		fields = append(fields, LogFieldTraceID, sc.TraceID().String())
+	}
+	if requestID, ok := RequestIDFromContext(ctx); ok && requestID != "" {
+		fields = append(fields, LogFieldRequestID, requestID)
+	}
+	if method, ok := grpc.Method(ctx); ok {
+		fields = append(fields, LogFieldMethod, method)
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		fields = append(fields, LogFieldPeer, p.Addr.String())
+	}
+	fields = append(fields, logFieldsFromContext(ctx)...)
+	return
+}
+
+// formatLogFields 将字段格式化为 key=value 形式
+func formatLogFields(fields []interface{}) string {
+	var b strings.Builder
+	for i := 0; i+1 < len(fields); i += 2 {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		fmt.Fprintf(&b, "%v=%v", fields[i], fields[i+1])
+	}
+	return b.String()
+}
@@ -1,8 +1,16 @@
 package ngrpc
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"fmt"
+	"io"
 	"log"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
 )
 
 // Logger logger
@@ -19,56 +27,224 @@ type Logger interface {
 	Fatalln(ctx context.Context, args ...interface{})
 }
 
+// LogLevel 日志级别
+type LogLevel int32
+
+const (
+	LogLevelDebug LogLevel = iota
+	LogLevelInfo
+	LogLevelWarn
+	LogLevelError
+	LogLevelFatal
+)
+
+func (l LogLevel) String() string {
+	switch l {
+	case LogLevelDebug:
+		return "debug"
+	case LogLevelInfo:
+		return "info"
+	case LogLevelWarn:
+		return "warn"
+	case LogLevelError:
+		return "error"
+	case LogLevelFatal:
+		return "fatal"
+	}
+	return fmt.Sprintf("LogLevel(%d)", int32(l))
+}
+
+// ParseLogLevel 解析日志级别
+func ParseLogLevel(level string) (LogLevel, error) {
+	switch strings.ToLower(level) {
+	case "debug":
+		return LogLevelDebug, nil
+	case "info":
+		return LogLevelInfo, nil
+	case "warn", "warning":
+		return LogLevelWarn, nil
+	case "error":
+		return LogLevelError, nil
+	case "fatal":
+		return LogLevelFatal, nil
+	}
+	return LogLevelDebug, fmt.Errorf("unknown log level %q", level)
+}
+
+// FatalPolicy Fatal 日志的处理方式
+type FatalPolicy int32
+
+const (
+	// FatalExit 输出日志后退出进程
+	FatalExit FatalPolicy = iota
+	// FatalPanic 输出日志后panic
+	FatalPanic
+	// FatalLogOnly 仅输出日志
+	FatalLogOnly
+)
+
+// StdLogger 标准库日志，自动输出上下文中的日志字段，零值输出所有级别到标准日志，Fatal 时退出进程；
+// 日志方法为值接收者，StdLogger{} 和 &StdLogger{} 都可作为 Logger，复制后共享级别和 Fatal 处理方式
 type StdLogger struct {
+	state  *stdLoggerState
+	json   bool
+	logger *log.Logger
+}
+
+// stdLoggerState 可在运行时修改的状态
+type stdLoggerState struct {
+	level atomic.Int32
+	fatal atomic.Int32
 }
 
-func (StdLogger) Debugf(ctx context.Context, format string, args ...interface{}) {
-	log.Printf("[Debug] "+format, args...)
+// NewStdLogger 创建标准库日志，out 为 nil 时使用标准日志输出，json 为 true 时每行输出一个 JSON 对象
+func NewStdLogger(level LogLevel, out io.Writer, json bool) *StdLogger {
+	l := &StdLogger{state: new(stdLoggerState), json: json}
+	l.state.level.Store(int32(level))
+	if json {
+		if out == nil {
+			out = os.Stderr
+		}
+		l.logger = log.New(out, "", 0)
+	} else if out != nil {
+		l.logger = log.New(out, "", log.LstdFlags)
+	}
+	return l
 }
-func (StdLogger) Debugln(ctx context.Context, args ...interface{}) {
-	nArgs := []interface{}{
-		"[Debug]",
+
+// init 零值首次设置时创建状态，零值应在开始使用前设置，运行时修改请使用 NewStdLogger 创建的日志
+func (l *StdLogger) init() *stdLoggerState {
+	if l.state == nil {
+		l.state = new(stdLoggerState)
 	}
-	nArgs = append(nArgs, args...)
-	log.Println(nArgs...)
+	return l.state
 }
-func (StdLogger) Infof(ctx context.Context, format string, args ...interface{}) {
-	log.Printf("[INFO] "+format, args...)
+
+// SetLevel 设置日志级别，可在运行时调用
+func (l *StdLogger) SetLevel(level LogLevel) {
+	l.init().level.Store(int32(level))
 }
-func (StdLogger) Infoln(ctx context.Context, args ...interface{}) {
-	nArgs := []interface{}{
-		"[INFO]",
+
+// Level 当前日志级别
+func (l StdLogger) Level() LogLevel {
+	if l.state == nil {
+		return LogLevelDebug
 	}
-	nArgs = append(nArgs, args...)
-	log.Println(nArgs...)
+	return LogLevel(l.state.level.Load())
 }
-func (StdLogger) Warnf(ctx context.Context, format string, args ...interface{}) {
-	log.Printf("[Warn] "+format, args...)
+
+// SetFatalPolicy 设置 Fatal 日志的处理方式
+func (l *StdLogger) SetFatalPolicy(policy FatalPolicy) {
+	l.init().fatal.Store(int32(policy))
 }
-func (StdLogger) Warnln(ctx context.Context, args ...interface{}) {
-	nArgs := []interface{}{
-		"[Warn]",
+
+func (l StdLogger) fatalPolicy() FatalPolicy {
+	if l.state == nil {
+		return FatalExit
 	}
-	nArgs = append(nArgs, args...)
-	log.Println(nArgs...)
+	return FatalPolicy(l.state.fatal.Load())
 }
-func (StdLogger) Errorf(ctx context.Context, format string, args ...interface{}) {
-	log.Printf("[Error] "+format, args...)
+
+var stdLevelPrefixes = [...]string{
+	LogLevelDebug: "[Debug]",
+	LogLevelInfo:  "[INFO]",
+	LogLevelWarn:  "[Warn]",
+	LogLevelError: "[Error]",
+	LogLevelFatal: "[Fatal]",
 }
-func (StdLogger) Errorln(ctx context.Context, args ...interface{}) {
-	nArgs := []interface{}{
-		"[Error]",
+
+func (l StdLogger) output(ctx context.Context, level LogLevel, msg string) {
+	if level < l.Level() {
+		return
+	}
+	var line string
+	if l.json {
+		line = jsonLogLine(ctx, level, msg)
+	} else {
+		line = stdLevelPrefixes[level] + " " + msg
+		if fields := LogFields(ctx); len(fields) > 0 {
+			line += " " + formatLogFields(fields)
+		}
+	}
+	if l.logger != nil {
+		l.logger.Println(line)
+	} else {
+		log.Println(line)
+	}
+	if level != LogLevelFatal {
+		return
+	}
+	switch l.fatalPolicy() {
+	case FatalPanic:
+		panic(msg)
+	case FatalLogOnly:
+	default:
+		os.Exit(1)
 	}
-	nArgs = append(nArgs, args...)
-	log.Println(nArgs...)
 }
-func (StdLogger) Fatalf(ctx context.Context, format string, args ...interface{}) {
-	log.Fatalf("[Fatal] "+format, args...)
+
+func jsonLogLine(ctx context.Context, level LogLevel, msg string) string {
+	var b bytes.Buffer
+	b.WriteString(`{"time":`)
+	writeJSONValue(&b, time.Now().Format(time.RFC3339Nano))
+	b.WriteString(`,"level":`)
+	writeJSONValue(&b, level.String())
+	b.WriteString(`,"msg":`)
+	writeJSONValue(&b, msg)
+	fields := LogFields(ctx)
+	for i := 0; i+1 < len(fields); i += 2 {
+		b.WriteByte(',')
+		writeJSONValue(&b, fmt.Sprint(fields[i]))
+		b.WriteByte(':')
+		writeJSONValue(&b, fields[i+1])
+	}
+	b.WriteByte('}')
+	return b.String()
 }
-func (StdLogger) Fatalln(ctx context.Context, args ...interface{}) {
-	nArgs := []interface{}{
-		"[Fatal]",
+
+func writeJSONValue(b *bytes.Buffer, v interface{}) {
+	if err, ok := v.(error); ok {
+		v = err.Error()
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		data, _ = json.Marshal(fmt.Sprint(v))
 	}
-	nArgs = append(nArgs, args...)
-	log.Fatalln(nArgs...)
+	b.Write(data)
+}
+
+func sprintln(args ...interface{}) string {
+	msg := fmt.Sprintln(args...)
+	return msg[:len(msg)-1]
+}
+
+func (l StdLogger) Debugf(ctx context.Context, format string, args ...interface{}) {
+	l.output(ctx, LogLevelDebug, fmt.Sprintf(format, args...))
+}
+func (l StdLogger) Debugln(ctx context.Context, args ...interface{}) {
+	l.output(ctx, LogLevelDebug, sprintln(args...))
+}
+func (l StdLogger) Infof(ctx context.Context, format string, args ...interface{}) {
+	l.output(ctx, LogLevelInfo, fmt.Sprintf(format, args...))
+}
+func (l StdLogger) Infoln(ctx context.Context, args ...interface{}) {
+	l.output(ctx, LogLevelInfo, sprintln(args...))
+}
+func (l StdLogger) Warnf(ctx context.Context, format string, args ...interface{}) {
+	l.output(ctx, LogLevelWarn, fmt.Sprintf(format, args...))
+}
+func (l StdLogger) Warnln(ctx context.Context, args ...interface{}) {
+	l.output(ctx, LogLevelWarn, sprintln(args...))
+}
+func (l StdLogger) Errorf(ctx context.Context, format string, args ...interface{}) {
+	l.output(ctx, LogLevelError, fmt.Sprintf(format, args...))
+}
+func (l StdLogger) Errorln(ctx context.Context, args ...interface{}) {
+	l.output(ctx, LogLevelError, sprintln(args...))
+}
+func (l StdLogger) Fatalf(ctx context.Context, format string, args ...interface{}) {
+	l.output(ctx, LogLevelFatal, fmt.Sprintf(format, args...))
+}
+func (l StdLogger) Fatalln(ctx context.Context, args ...interface{}) {
+	l.output(ctx, LogLevelFatal, sprintln(args...))
 }
@@ -0,0 +1,81 @@
+package ngrpc
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"google.golang.org/grpc/grpclog"
+)
+
+// grpcLogger 将 grpc-go 内部日志转发到 Logger
+type grpcLogger struct {
+	logger    Logger
+	verbosity int
+	// info 为 false 时丢弃 Info 日志
+	info bool
+	// exit Fatal 日志输出后调用，Logger 的 Fatal 处理方式为仅输出日志时同样退出
+	exit func(code int)
+}
+
+// NewGrpcLoggerV2 创建由 Logger 输出的 grpclog.LoggerV2，转发全部级别，verbosity 对应 grpclog 的 V 级别
+func NewGrpcLoggerV2(logger Logger, verbosity int) grpclog.LoggerV2 {
+	return &grpcLogger{logger: logger, verbosity: verbosity, info: true, exit: os.Exit}
+}
+
+// SetGrpcLogger 将 grpc-go 内部 Warning 及以上的日志交给 Logger 输出；
+// grpclog 为进程级全局设置且不是并发安全的，只能在 main 中任何 grpc 调用之前调用一次，
+// 因此不由 WithServerLogger、WithClientLogger 设置；
+// 需要 Info 日志时使用 grpclog.SetLoggerV2(NewGrpcLoggerV2(logger, verbosity))
+func SetGrpcLogger(logger Logger) {
+	grpclog.SetLoggerV2(&grpcLogger{logger: logger, exit: os.Exit})
+}
+
+func (g *grpcLogger) Info(args ...interface{}) {
+	if g.info {
+		g.logger.Infof(context.Background(), "%s", fmt.Sprint(args...))
+	}
+}
+func (g *grpcLogger) Infoln(args ...interface{}) {
+	if g.info {
+		g.logger.Infoln(context.Background(), args...)
+	}
+}
+func (g *grpcLogger) Infof(format string, args ...interface{}) {
+	if g.info {
+		g.logger.Infof(context.Background(), format, args...)
+	}
+}
+func (g *grpcLogger) Warning(args ...interface{}) {
+	g.logger.Warnf(context.Background(), "%s", fmt.Sprint(args...))
+}
+func (g *grpcLogger) Warningln(args ...interface{}) {
+	g.logger.Warnln(context.Background(), args...)
+}
+func (g *grpcLogger) Warningf(format string, args ...interface{}) {
+	g.logger.Warnf(context.Background(), format, args...)
+}
+func (g *grpcLogger) Error(args ...interface{}) {
+	g.logger.Errorf(context.Background(), "%s", fmt.Sprint(args...))
+}
+func (g *grpcLogger) Errorln(args ...interface{}) {
+	g.logger.Errorln(context.Background(), args...)
+}
+func (g *grpcLogger) Errorf(format string, args ...interface{}) {
+	g.logger.Errorf(context.Background(), format, args...)
+}
+func (g *grpcLogger) Fatal(args ...interface{}) {
+	g.logger.Fatalf(context.Background(), "%s", fmt.Sprint(args...))
+	g.exit(1)
+}
+func (g *grpcLogger) Fatalln(args ...interface{}) {
+	g.logger.Fatalln(context.Background(), args...)
+	g.exit(1)
+}
+func (g *grpcLogger) Fatalf(format string, args ...interface{}) {
+	g.logger.Fatalf(context.Background(), format, args...)
+	g.exit(1)
+}
+func (g *grpcLogger) V(l int) bool {
+	return l <= g.verbosity
+}
@@ -0,0 +1,182 @@
+package ngrpc
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// LevelFatal slog 中 Fatal 对应的级别
+const LevelFatal = slog.LevelError + 4
+
+// SlogLogger 基于 log/slog 的 Logger
+type SlogLogger struct {
+	logger *slog.Logger
+}
+
+// NewSlogLogger 创建基于 log/slog 的 Logger
+func NewSlogLogger(logger *slog.Logger) *SlogLogger {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &SlogLogger{logger: logger}
+}
+
+// Level 底层 slog.Logger 启用的最低级别
+func (l *SlogLogger) Level() LogLevel {
+	ctx := context.Background()
+	for _, level := range []slog.Level{slog.LevelDebug, slog.LevelInfo, slog.LevelWarn, slog.LevelError} {
+		if l.logger.Enabled(ctx, level) {
+			return slogLogLevel(level)
+		}
+	}
+	return LogLevelFatal
+}
+
+func (l *SlogLogger) log(ctx context.Context, level slog.Level, msg string) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	l.logger.Log(ctx, level, msg, LogFields(ctx)...)
+}
+
+func (l *SlogLogger) Debugf(ctx context.Context, format string, args ...interface{}) {
+	l.log(ctx, slog.LevelDebug, fmt.Sprintf(format, args...))
+}
+func (l *SlogLogger) Debugln(ctx context.Context, args ...interface{}) {
+	l.log(ctx, slog.LevelDebug, sprintln(args...))
+}
+func (l *SlogLogger) Infof(ctx context.Context, format string, args ...interface{}) {
+	l.log(ctx, slog.LevelInfo, fmt.Sprintf(format, args...))
+}
+func (l *SlogLogger) Infoln(ctx context.Context, args ...interface{}) {
+	l.log(ctx, slog.LevelInfo, sprintln(args...))
+}
+func (l *SlogLogger) Warnf(ctx context.Context, format string, args ...interface{}) {
+	l.log(ctx, slog.LevelWarn, fmt.Sprintf(format, args...))
+}
+func (l *SlogLogger) Warnln(ctx context.Context, args ...interface{}) {
+	l.log(ctx, slog.LevelWarn, sprintln(args...))
+}
+func (l *SlogLogger) Errorf(ctx context.Context, format string, args ...interface{}) {
+	l.log(ctx, slog.LevelError, fmt.Sprintf(format, args...))
+}
+func (l *SlogLogger) Errorln(ctx context.Context, args ...interface{}) {
+	l.log(ctx, slog.LevelError, sprintln(args...))
+}
+func (l *SlogLogger) Fatalf(ctx context.Context, format string, args ...interface{}) {
+	l.log(ctx, LevelFatal, fmt.Sprintf(format, args...))
+	os.Exit(1)
+}
+func (l *SlogLogger) Fatalln(ctx context.Context, args ...interface{}) {
+	l.log(ctx, LevelFatal, sprintln(args...))
+	os.Exit(1)
+}
+
+// slogHandler 将 slog 记录转发到 Logger
+type slogHandler struct {
+	logger Logger
+	attrs  []slog.Attr
+	group  string
+}
+
+// NewSlogHandler 创建由 Logger 输出的 slog.Handler，Fatal 级别按 Error 输出，不会退出进程；
+// Logger 实现了 Level() LogLevel（如 StdLogger 和内置适配器）时低于该级别的记录不会格式化
+func NewSlogHandler(logger Logger) slog.Handler {
+	return &slogHandler{logger: logger}
+}
+
+// leveledLogger 可以获取当前级别的 Logger
+type leveledLogger interface {
+	Level() LogLevel
+}
+
+// Enabled Logger 实现了 Level 时按其级别过滤，否则全部交给 Logger
+func (h *slogHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	l, ok := h.logger.(leveledLogger)
+	return !ok || slogLogLevel(level) >= l.Level()
+}
+
+// slogLogLevel slog 级别对应的输出级别，Fatal 按 Error 输出
+func slogLogLevel(level slog.Level) LogLevel {
+	switch {
+	case level >= slog.LevelError:
+		return LogLevelError
+	case level >= slog.LevelWarn:
+		return LogLevelWarn
+	case level >= slog.LevelInfo:
+		return LogLevelInfo
+	}
+	return LogLevelDebug
+}
+
+func (h *slogHandler) Handle(ctx context.Context, record slog.Record) error {
+	var b strings.Builder
+	b.WriteString(record.Message)
+	for _, attr := range h.attrs {
+		writeSlogAttr(&b, "", attr)
+	}
+	record.Attrs(func(attr slog.Attr) bool {
+		writeSlogAttr(&b, h.group, attr)
+		return true
+	})
+	msg := b.String()
+	switch slogLogLevel(record.Level) {
+	case LogLevelError:
+		h.logger.Errorf(ctx, "%s", msg)
+	case LogLevelWarn:
+		h.logger.Warnf(ctx, "%s", msg)
+	case LogLevelInfo:
+		h.logger.Infof(ctx, "%s", msg)
+	default:
+		h.logger.Debugf(ctx, "%s", msg)
+	}
+	return nil
+}
+
+func (h *slogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	nh := *h
+	nh.attrs = append([]slog.Attr(nil), h.attrs...)
+	for _, attr := range attrs {
+		if h.group != "" {
+			attr.Key = h.group + "." + attr.Key
+		}
+		nh.attrs = append(nh.attrs, attr)
+	}
+	return &nh
+}
+
+func (h *slogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	nh := *h
+	if h.group != "" {
+		nh.group = h.group + "." + name
+	} else {
+		nh.group = name
+	}
+	return &nh
+}
+
+func writeSlogAttr(b *strings.Builder, group string, attr slog.Attr) {
+	attr.Value = attr.Value.Resolve()
+	if attr.Equal(slog.Attr{}) {
+		return
+	}
+	key := attr.Key
+	if group != "" && key != "" {
+		key = group + "." + key
+	} else if key == "" {
+		key = group
+	}
+	if attr.Value.Kind() == slog.KindGroup {
+		for _, a := range attr.Value.Group() {
+			writeSlogAttr(b, key, a)
+		}
+		return
+	}
+	fmt.Fprintf(b, " %s=%v", key, attr.Value.Any())
+}
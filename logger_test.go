@@ -0,0 +1,147 @@
+package ngrpc
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// logAll 每个级别各输出一条日志，Fatal 除外
+func logAll(logger Logger, ctx context.Context) {
+	logger.Debugf(ctx, "debug %d", 1)
+	logger.Infof(ctx, "info %d", 2)
+	logger.Warnln(ctx, "warn", 3)
+	logger.Errorf(ctx, "error %d", 4)
+}
+
+func TestSlogLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewSlogLogger(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelInfo})))
+	if level := logger.Level(); level != LogLevelInfo {
+		t.Fatalf("Level() = %s, want info", level)
+	}
+	logAll(logger, WithLogFields(context.Background(), "request_id", "r1"))
+	out := buf.String()
+	if strings.Contains(out, "debug 1") {
+		t.Fatalf("debug record was written:\n%s", out)
+	}
+	for _, want := range []string{"level=INFO msg=\"info 2\" request_id=r1", "level=WARN msg=\"warn 3\"", "level=ERROR msg=\"error 4\""} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestZapLogger(t *testing.T) {
+	core, logs := observer.New(zapcore.WarnLevel)
+	logger := NewZapLogger(zap.New(core))
+	if level := logger.Level(); level != LogLevelWarn {
+		t.Fatalf("Level() = %s, want warn", level)
+	}
+	logAll(logger, WithLogFields(context.Background(), "request_id", "r1"))
+	entries := logs.AllUntimed()
+	if len(entries) != 2 {
+		t.Fatalf("got %d entries, want warn and error", len(entries))
+	}
+	if entries[0].Message != "warn 3" || entries[0].Level != zapcore.WarnLevel || entries[1].Message != "error 4" {
+		t.Fatalf("entries = %+v", entries)
+	}
+	if got := entries[0].ContextMap()["request_id"]; got != "r1" {
+		t.Fatalf("request_id = %v, want r1", got)
+	}
+	if level := NewZapLogger(nil).Level(); level != LogLevelFatal {
+		t.Fatalf("nop Level() = %s, want fatal", level)
+	}
+}
+
+func TestZerologLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewZerologLogger(zerolog.New(&buf).Level(zerolog.InfoLevel))
+	if level := logger.Level(); level != LogLevelInfo {
+		t.Fatalf("Level() = %s, want info", level)
+	}
+	logAll(logger, WithLogFields(context.Background(), "request_id", "r1"))
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	want := []string{
+		`{"level":"info","request_id":"r1","message":"info 2"}`,
+		`{"level":"warn","request_id":"r1","message":"warn 3"}`,
+		`{"level":"error","request_id":"r1","message":"error 4"}`,
+	}
+	if strings.Join(lines, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("output:\n%s\nwant:\n%s", buf.String(), strings.Join(want, "\n"))
+	}
+}
+
+func TestSlogHandler(t *testing.T) {
+	var buf bytes.Buffer
+	std := NewStdLogger(LogLevelWarn, &buf, false)
+	handler := NewSlogHandler(std)
+	ctx := context.Background()
+	if handler.Enabled(ctx, slog.LevelInfo) || !handler.Enabled(ctx, slog.LevelWarn) || !handler.Enabled(ctx, LevelFatal) {
+		t.Fatal("Enabled does not follow the logger level")
+	}
+	logger := slog.New(handler).With("service", "a").WithGroup("req")
+	logger.Info("hidden")
+	logger.Warn("slow", "method", "/pkg.Service/Call", slog.Group("peer", "ip", "10.0.0.1"))
+	out := buf.String()
+	if strings.Contains(out, "hidden") {
+		t.Fatalf("info record was written:\n%s", out)
+	}
+	if want := "[Warn] slow service=a req.method=/pkg.Service/Call req.peer.ip=10.0.0.1"; !strings.Contains(out, want) {
+		t.Fatalf("output missing %q:\n%s", want, out)
+	}
+	// 级别在运行时修改后立即生效
+	std.SetLevel(LogLevelDebug)
+	if !handler.Enabled(ctx, slog.LevelDebug) {
+		t.Fatal("debug disabled after SetLevel(debug)")
+	}
+	// 没有 Level 方法的 Logger 全部放行
+	if !NewSlogHandler(nopLogger{}).Enabled(ctx, slog.LevelDebug) {
+		t.Fatal("logger without Level should accept every record")
+	}
+}
+
+func TestGrpcLogger(t *testing.T) {
+	var buf bytes.Buffer
+	std := NewStdLogger(LogLevelDebug, &buf, false)
+	std.SetFatalPolicy(FatalLogOnly)
+	var exits []int
+	exit := func(code int) { exits = append(exits, code) }
+
+	// SetGrpcLogger 使用的桥接丢弃 Info 日志
+	bridge := &grpcLogger{logger: std, exit: exit}
+	bridge.Info("dropped")
+	bridge.Warningf("warn %d", 1)
+	bridge.Errorln("error", 2)
+	if bridge.V(1) {
+		t.Fatal("V(1) enabled with verbosity 0")
+	}
+	out := buf.String()
+	if strings.Contains(out, "dropped") || !strings.Contains(out, "[Warn] warn 1") || !strings.Contains(out, "[Error] error 2") {
+		t.Fatalf("unexpected output:\n%s", out)
+	}
+
+	v2 := NewGrpcLoggerV2(std, 2).(*grpcLogger)
+	v2.exit = exit
+	v2.Infof("info %d", 3)
+	if !strings.Contains(buf.String(), "[INFO] info 3") || !v2.V(2) || v2.V(3) {
+		t.Fatalf("verbosity or info output wrong:\n%s", buf.String())
+	}
+	// Fatal 输出后总是退出，即使 Logger 本身只输出日志
+	v2.Fatal("fatal")
+	v2.Fatalf("fatal %d", 4)
+	v2.Fatalln("fatal", 5)
+	if len(exits) != 3 || exits[0] != 1 || exits[1] != 1 || exits[2] != 1 {
+		t.Fatalf("exits = %v, want three exit(1)", exits)
+	}
+	if !strings.Contains(buf.String(), "[Fatal] fatal 4") {
+		t.Fatalf("fatal not logged:\n%s", buf.String())
+	}
+}
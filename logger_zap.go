@@ -0,0 +1,77 @@
+package ngrpc
+
+import (
+	"context"
+	"fmt"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// ZapLogger 基于 zap 的 Logger
+type ZapLogger struct {
+	logger *zap.Logger
+}
+
+// NewZapLogger 创建基于 zap 的 Logger
+func NewZapLogger(logger *zap.Logger) *ZapLogger {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &ZapLogger{logger: logger.WithOptions(zap.AddCallerSkip(1))}
+}
+
+// Level 底层 zap.Logger 启用的最低级别
+func (l *ZapLogger) Level() LogLevel {
+	switch level := zapcore.LevelOf(l.logger.Core()); {
+	case level <= zapcore.DebugLevel:
+		return LogLevelDebug
+	case level == zapcore.InfoLevel:
+		return LogLevelInfo
+	case level == zapcore.WarnLevel:
+		return LogLevelWarn
+	case level == zapcore.ErrorLevel:
+		return LogLevelError
+	}
+	return LogLevelFatal
+}
+
+func zapFields(ctx context.Context) []zap.Field {
+	fields := LogFields(ctx)
+	zfs := make([]zap.Field, 0, len(fields)/2)
+	for i := 0; i+1 < len(fields); i += 2 {
+		zfs = append(zfs, zap.Any(fmt.Sprint(fields[i]), fields[i+1]))
+	}
+	return zfs
+}
+
+func (l *ZapLogger) Debugf(ctx context.Context, format string, args ...interface{}) {
+	l.logger.Debug(fmt.Sprintf(format, args...), zapFields(ctx)...)
+}
+func (l *ZapLogger) Debugln(ctx context.Context, args ...interface{}) {
+	l.logger.Debug(sprintln(args...), zapFields(ctx)...)
+}
+func (l *ZapLogger) Infof(ctx context.Context, format string, args ...interface{}) {
+	l.logger.Info(fmt.Sprintf(format, args...), zapFields(ctx)...)
+}
+func (l *ZapLogger) Infoln(ctx context.Context, args ...interface{}) {
+	l.logger.Info(sprintln(args...), zapFields(ctx)...)
+}
+func (l *ZapLogger) Warnf(ctx context.Context, format string, args ...interface{}) {
+	l.logger.Warn(fmt.Sprintf(format, args...), zapFields(ctx)...)
+}
+func (l *ZapLogger) Warnln(ctx context.Context, args ...interface{}) {
+	l.logger.Warn(sprintln(args...), zapFields(ctx)...)
+}
+func (l *ZapLogger) Errorf(ctx context.Context, format string, args ...interface{}) {
+	l.logger.Error(fmt.Sprintf(format, args...), zapFields(ctx)...)
+}
+func (l *ZapLogger) Errorln(ctx context.Context, args ...interface{}) {
+	l.logger.Error(sprintln(args...), zapFields(ctx)...)
+}
+func (l *ZapLogger) Fatalf(ctx context.Context, format string, args ...interface{}) {
+	l.logger.Fatal(fmt.Sprintf(format, args...), zapFields(ctx)...)
+}
+func (l *ZapLogger) Fatalln(ctx context.Context, args ...interface{}) {
+	l.logger.Fatal(sprintln(args...), zapFields(ctx)...)
+}
@@ -0,0 +1,68 @@
+package ngrpc
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rs/zerolog"
+)
+
+// ZerologLogger 基于 zerolog 的 Logger
+type ZerologLogger struct {
+	logger zerolog.Logger
+}
+
+// NewZerologLogger 创建基于 zerolog 的 Logger
+func NewZerologLogger(logger zerolog.Logger) *ZerologLogger {
+	return &ZerologLogger{logger: logger}
+}
+
+// Level 底层 zerolog.Logger 与全局级别中较高者
+func (l *ZerologLogger) Level() LogLevel {
+	switch max(l.logger.GetLevel(), zerolog.GlobalLevel()) {
+	case zerolog.TraceLevel, zerolog.DebugLevel:
+		return LogLevelDebug
+	case zerolog.InfoLevel:
+		return LogLevelInfo
+	case zerolog.WarnLevel:
+		return LogLevelWarn
+	case zerolog.ErrorLevel:
+		return LogLevelError
+	}
+	return LogLevelFatal
+}
+
+func (l *ZerologLogger) msg(ctx context.Context, e *zerolog.Event, msg string) {
+	e.Fields(LogFields(ctx)).Msg(msg)
+}
+
+func (l *ZerologLogger) Debugf(ctx context.Context, format string, args ...interface{}) {
+	l.msg(ctx, l.logger.Debug(), fmt.Sprintf(format, args...))
+}
+func (l *ZerologLogger) Debugln(ctx context.Context, args ...interface{}) {
+	l.msg(ctx, l.logger.Debug(), sprintln(args...))
+}
+func (l *ZerologLogger) Infof(ctx context.Context, format string, args ...interface{}) {
+	l.msg(ctx, l.logger.Info(), fmt.Sprintf(format, args...))
+}
+func (l *ZerologLogger) Infoln(ctx context.Context, args ...interface{}) {
+	l.msg(ctx, l.logger.Info(), sprintln(args...))
+}
+func (l *ZerologLogger) Warnf(ctx context.Context, format string, args ...interface{}) {
+	l.msg(ctx, l.logger.Warn(), fmt.Sprintf(format, args...))
+}
+func (l *ZerologLogger) Warnln(ctx context.Context, args ...interface{}) {
+	l.msg(ctx, l.logger.Warn(), sprintln(args...))
+}
+func (l *ZerologLogger) Errorf(ctx context.Context, format string, args ...interface{}) {
+	l.msg(ctx, l.logger.Error(), fmt.Sprintf(format, args...))
+}
+func (l *ZerologLogger) Errorln(ctx context.Context, args ...interface{}) {
+	l.msg(ctx, l.logger.Error(), sprintln(args...))
+}
+func (l *ZerologLogger) Fatalf(ctx context.Context, format string, args ...interface{}) {
+	l.msg(ctx, l.logger.Fatal(), fmt.Sprintf(format, args...))
+}
+func (l *ZerologLogger) Fatalln(ctx context.Context, args ...interface{}) {
+	l.msg(ctx, l.logger.Fatal(), sprintln(args...))
+}
@@ -0,0 +1,121 @@
+package ngrpc
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// WithServerHTTPHandler 在同一端口上同时提供 gRPC 和 HTTP 服务，
+// HTTP/2 且 content-type 为 application/grpc 的请求交给 gRPC，其余请求交给 handler；
+// 该模式下由 net/http 处理连接，TLS 使用 WithServerHTTPTLSConfig，设置了 Credentials 的监听在 Run 时返回错误；
+// keepalive、连接最长存活时间和最大并发流数同样不生效，设置后 Run 时返回错误
+func WithServerHTTPHandler(handler http.Handler) ServerOption {
+	return func(o *ServerOptions) {
+		o.HTTPHandler = handler
+	}
+}
+
+// WithServerHTTPTLSConfig HTTP 复用模式下的 TLS 配置，通过 ALPN 协商 h2 和 http/1.1
+func WithServerHTTPTLSConfig(config *tls.Config) ServerOption {
+	return func(o *ServerOptions) {
+		o.HTTPTLSConfig = config
+	}
+}
+
+// isGrpcRequest 判断是否为 gRPC 请求
+func isGrpcRequest(r *http.Request) bool {
+	contentType := r.Header.Get("Content-Type")
+	return r.ProtoMajor == 2 && strings.HasPrefix(contentType, "application/grpc") &&
+		!strings.HasPrefix(contentType, "application/grpc-web")
+}
+
+// httpHandler 按请求类型分发到 gRPC、gRPC-Web/Connect 或 HTTP handler
+func (s *GrpcServer) httpHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isGrpcRequest(r) {
+			s.server.ServeHTTP(w, r)
+			return
+		}
+		if s.opts.Web != nil {
+			if s.isPreflight(r) {
+				s.servePreflight(w, r)
+				return
+			}
+			if protocol := s.webProtocolOf(r); protocol != 0 {
+				s.serveWeb(w, r, protocol)
+				return
+			}
+		}
+		if s.opts.HTTPHandler == nil {
+			http.NotFound(w, r)
+			return
+		}
+		s.opts.HTTPHandler.ServeHTTP(w, r)
+	})
+}
+
+// newHTTPServer 创建支持 HTTP/1.1、h2c 和 h2 的 HTTP 服务，tlsConfig 不为nil时使用 TLS
+func (s *GrpcServer) newHTTPServer(handler http.Handler, tlsConfig *tls.Config) *http.Server {
+	protocols := new(http.Protocols)
+	protocols.SetHTTP1(true)
+	protocols.SetHTTP2(true)
+	protocols.SetUnencryptedHTTP2(true)
+	server := &http.Server{
+		Handler:   handler,
+		Protocols: protocols,
+	}
+	if tlsConfig != nil {
+		server.TLSConfig = tlsConfig.Clone()
+	}
+	s.mu.Lock()
+	s.httpServers = append(s.httpServers, server)
+	s.mu.Unlock()
+	return server
+}
+
+// serveHTTP 在 listener 上提供 HTTP 服务，关闭时返回 nil
+func serveHTTP(server *http.Server, lis net.Listener) (err error) {
+	if server.TLSConfig != nil {
+		err = server.ServeTLS(lis, "", "")
+	} else {
+		err = server.Serve(lis)
+	}
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return
+}
+
+// closeHTTPServers 关闭全部 HTTP 服务
+func (s *GrpcServer) closeHTTPServers() {
+	s.mu.Lock()
+	servers := s.httpServers
+	s.httpServers = nil
+	s.mu.Unlock()
+	for _, server := range servers {
+		if err := server.Close(); err != nil {
+			s.opts.Log.Errorf(s.ctx, "%s http server close: %v", s.opts.Name, err)
+		}
+	}
+}
+
+// shutdownHTTPServers 优雅关闭全部 HTTP 服务，ctx 到期后强制关闭
+func (s *GrpcServer) shutdownHTTPServers(ctx context.Context) error {
+	s.mu.Lock()
+	servers := s.httpServers
+	s.httpServers = nil
+	s.mu.Unlock()
+	var errs []error
+	for _, server := range servers {
+		if err := server.Shutdown(ctx); err != nil {
+			server.Close()
+			errs = append(errs, fmt.Errorf("%s http server shutdown: %w", s.opts.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
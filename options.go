@@ -1,10 +1,19 @@
 package ngrpc
 
 import (
+	"crypto/tls"
+	"net"
+	"net/http"
 	"os"
 
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
 	"github.com/nilorg/ngrpc/v2/resolver"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/keepalive"
 )
 
 // ServerOptions 可选参数列表
@@ -16,6 +25,53 @@ type ServerOptions struct {
 	UnaryServerInterceptors  []grpc.UnaryServerInterceptor
 	register                 resolver.Registry
 	RandomPort               bool
+	TracerProvider           trace.TracerProvider
+	MeterProvider            metric.MeterProvider
+	Credentials              credentials.TransportCredentials
+	RateLimiter              *RateLimiter
+	AdaptiveLimiter          *AdaptiveLimiter
+	DeadlineLimit            *DeadlineLimit
+	Listener                 net.Listener
+	Listeners                []ServerListener
+	HTTPHandler              http.Handler
+	HTTPTLSConfig            *tls.Config
+	Web                      *WebOptions
+	AdminAddress             string
+	Reflection               bool
+	Keepalive                keepalive.ServerParameters
+	KeepalivePolicy          keepalive.EnforcementPolicy
+	ClientKeepalive          *keepalive.ClientParameters
+	MaxRecvMsgSize           int
+	MaxSendMsgSize           int
+	MaxConcurrentStreams     uint32
+	AllowedMethods           []string
+	GatewayAddress           string
+	gatewayRegisters         []GatewayRegisterFunc
+	gatewayMuxOptions        []runtime.ServeMuxOption
+	SocketMode               os.FileMode
+	// 内置功能的拦截器，先于用户拦截器执行
+	unaryInterceptors  []grpc.UnaryServerInterceptor
+	streamInterceptors []grpc.StreamServerInterceptor
+	// hooks 在服务端创建完成后执行
+	hooks []func(s *GrpcServer)
+}
+
+// serverCredentials 额外的监听指定了凭证或启用 gateway 时按监听选择凭证
+func (o *ServerOptions) serverCredentials() credentials.TransportCredentials {
+	perListener := o.GatewayAddress != ""
+	for _, lis := range o.Listeners {
+		if lis.Credentials != nil {
+			perListener = true
+		}
+	}
+	if !perListener {
+		return o.Credentials
+	}
+	defaultCreds := o.Credentials
+	if defaultCreds == nil {
+		defaultCreds = insecure.NewCredentials()
+	}
+	return &listenerCredentials{defaultCreds: defaultCreds}
 }
 
 // ServerOption 为可选参数赋值的函数
@@ -28,10 +84,12 @@ func NewServerOptions(opts ...ServerOption) ServerOptions {
 		hostname = "unknown"
 	}
 	opt := ServerOptions{
-		Name:       hostname,
-		Address:    ":5000",
-		RandomPort: false,
-		Log:        new(StdLogger),
+		Name:            hostname,
+		Address:         ":5000",
+		RandomPort:      false,
+		Log:             NewStdLogger(LogLevelDebug, nil, false),
+		Reflection:      true,
+		KeepalivePolicy: DefaultServerKeepalivePolicy,
 	}
 	for _, o := range opts {
 		o(&opt)
@@ -51,6 +109,7 @@ func WithServerAddress(address string) ServerOption {
 	}
 }
 
+// WithServerLogger 服务端日志，grpc-go 内部日志是进程级全局设置，需在 main 中调用 SetGrpcLogger
 func WithServerLogger(log Logger) ServerOption {
 	return func(o *ServerOptions) {
 		o.Log = log
@@ -90,6 +149,17 @@ type ClientOptions struct {
 	UnaryClientInterceptors  []grpc.UnaryClientInterceptor
 	discovery                resolver.Discovery
 	dialOptions              []grpc.DialOption
+	TracerProvider           trace.TracerProvider
+	MeterProvider            metric.MeterProvider
+	PerRPCCredentials        credentials.PerRPCCredentials
+	Credentials              credentials.TransportCredentials
+	Timeouts                 ClientTimeouts
+	Keepalive                keepalive.ClientParameters
+	KeepalivePolicy          *keepalive.EnforcementPolicy
+	RetryPolicy              *RetryPolicy
+	// 内置功能的拦截器，先于用户拦截器执行
+	unaryInterceptors  []grpc.UnaryClientInterceptor
+	streamInterceptors []grpc.StreamClientInterceptor
 }
 
 // ClientOption 为可选参数赋值的函数
@@ -98,9 +168,10 @@ type ClientOption func(*ClientOptions)
 // NewClientOptions 创建可选参数
 func NewClientOptions(opts ...ClientOption) ClientOptions {
 	opt := ClientOptions{
-		Name:    "unknown",
-		Address: ":5000",
-		Log:     new(StdLogger),
+		Name:      "unknown",
+		Address:   ":5000",
+		Log:       NewStdLogger(LogLevelDebug, nil, false),
+		Keepalive: DefaultClientKeepalive,
 	}
 	for _, o := range opts {
 		o(&opt)
@@ -120,6 +191,7 @@ func WithClientAddress(address string) ClientOption {
 	}
 }
 
+// WithClientLogger 客户端日志，grpc-go 内部日志是进程级全局设置，需在 main 中调用 SetGrpcLogger
 func WithClientLogger(log Logger) ClientOption {
 	return func(o *ClientOptions) {
 		o.Log = log
@@ -0,0 +1,143 @@
+package ngrpc
+
+import (
+	"context"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// pipeListener 进程内监听，DialContext 创建的连接由 Accept 返回
+type pipeListener struct {
+	size  int
+	conns chan net.Conn
+	done  chan struct{}
+	once  sync.Once
+}
+
+// newPipeListener 创建进程内监听，size 为每个方向的缓冲区大小
+func newPipeListener(size int) *pipeListener {
+	return &pipeListener{
+		size:  size,
+		conns: make(chan net.Conn),
+		done:  make(chan struct{}),
+	}
+}
+
+func (l *pipeListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case <-l.done:
+		return nil, net.ErrClosed
+	}
+}
+
+func (l *pipeListener) Close() error {
+	l.once.Do(func() {
+		close(l.done)
+	})
+	return nil
+}
+
+func (l *pipeListener) Addr() net.Addr {
+	return pipeAddr{}
+}
+
+// DialContext 创建连接到该监听的连接
+func (l *pipeListener) DialContext(ctx context.Context) (net.Conn, error) {
+	c2s, s2c := newPipeBuffer(l.size), newPipeBuffer(l.size)
+	client := &pipeConn{r: s2c, w: c2s}
+	server := &pipeConn{r: c2s, w: s2c}
+	select {
+	case l.conns <- server:
+		return client, nil
+	case <-l.done:
+		return nil, net.ErrClosed
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// pipeAddr 进程内连接的地址
+type pipeAddr struct{}
+
+func (pipeAddr) Network() string { return "pipe" }
+func (pipeAddr) String() string  { return "pipe" }
+
+// pipeBuffer 单向有界缓冲区，写满时阻塞
+type pipeBuffer struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	buf    []byte
+	size   int
+	closed bool
+}
+
+func newPipeBuffer(size int) *pipeBuffer {
+	b := &pipeBuffer{size: size}
+	b.cond = sync.NewCond(&b.mu)
+	return b
+}
+
+func (b *pipeBuffer) read(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for len(b.buf) == 0 && !b.closed {
+		b.cond.Wait()
+	}
+	if len(b.buf) == 0 {
+		return 0, io.EOF
+	}
+	n := copy(p, b.buf)
+	b.buf = b.buf[n:]
+	b.cond.Broadcast()
+	return n, nil
+}
+
+func (b *pipeBuffer) write(p []byte) (n int, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for len(p) > 0 {
+		for len(b.buf) >= b.size && !b.closed {
+			b.cond.Wait()
+		}
+		if b.closed {
+			return n, io.ErrClosedPipe
+		}
+		m := min(len(p), b.size-len(b.buf))
+		b.buf = append(b.buf, p[:m]...)
+		p = p[m:]
+		n += m
+		b.cond.Broadcast()
+	}
+	return n, nil
+}
+
+func (b *pipeBuffer) close() {
+	b.mu.Lock()
+	b.closed = true
+	b.cond.Broadcast()
+	b.mu.Unlock()
+}
+
+// pipeConn 进程内连接，不支持超时
+type pipeConn struct {
+	r, w *pipeBuffer
+}
+
+func (c *pipeConn) Read(p []byte) (int, error)  { return c.r.read(p) }
+func (c *pipeConn) Write(p []byte) (int, error) { return c.w.write(p) }
+
+func (c *pipeConn) Close() error {
+	c.r.close()
+	c.w.close()
+	return nil
+}
+
+func (c *pipeConn) LocalAddr() net.Addr                { return pipeAddr{} }
+func (c *pipeConn) RemoteAddr() net.Addr               { return pipeAddr{} }
+func (c *pipeConn) SetDeadline(t time.Time) error      { return nil }
+func (c *pipeConn) SetReadDeadline(t time.Time) error  { return nil }
+func (c *pipeConn) SetWriteDeadline(t time.Time) error { return nil }
@@ -0,0 +1,347 @@
+package ngrpc
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"reflect"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// rateLimitClientIdle 客户端限流器空闲多久后回收
+	rateLimitClientIdle = 10 * time.Minute
+	// defaultRateLimitMaxClients 默认最多单独限流的客户端数
+	defaultRateLimitMaxClients = 10000
+)
+
+// RateLimit 令牌桶参数，Rate 为每秒请求数，Burst 为桶容量，Rate 小于等于0表示不限流
+type RateLimit struct {
+	Rate  float64 `yaml:"rate" json:"rate"`
+	Burst int     `yaml:"burst" json:"burst"`
+}
+
+func (l RateLimit) enabled() bool {
+	return l.Rate > 0
+}
+
+func (l RateLimit) newLimiter() *rate.Limiter {
+	burst := l.Burst
+	if burst <= 0 {
+		burst = 1
+	}
+	return rate.NewLimiter(rate.Limit(l.Rate), burst)
+}
+
+// ClientKeyFunc 获取调用方标识，用于按客户端限流，返回空字符串时不限流
+type ClientKeyFunc func(ctx context.Context) string
+
+// PeerIPClientKey 按对端IP区分客户端
+func PeerIPClientKey(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
+
+// MetadataClientKey 按 metadata 的值区分客户端，如 APIKeyHeader
+func MetadataClientKey(key string) ClientKeyFunc {
+	return func(ctx context.Context) string {
+		md, _ := metadata.FromIncomingContext(ctx)
+		return firstMetadata(md, key)
+	}
+}
+
+// RateLimitConfig 限流配置，Methods 的键支持 /pkg.Service/* 形式，匹配最长的规则
+type RateLimitConfig struct {
+	Global    RateLimit            `yaml:"global" json:"global"`
+	Methods   map[string]RateLimit `yaml:"methods" json:"methods"`
+	PerClient RateLimit            `yaml:"per_client" json:"per_client"`
+	// MaxClients 最多单独限流的客户端数，默认10000，超出后新的客户端共用一个令牌桶，
+	// 避免客户端标识由调用方控制时内存无限增长
+	MaxClients int           `yaml:"max_clients" json:"max_clients"`
+	ClientKey  ClientKeyFunc `yaml:"-" json:"-"`
+}
+
+// LoadRateLimitConfig 从YAML或JSON文件加载限流配置
+func LoadRateLimitConfig(path string) (*RateLimitConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseRateLimitConfig(data)
+}
+
+// ParseRateLimitConfig 解析YAML或JSON格式的限流配置，不允许未知字段
+func ParseRateLimitConfig(data []byte) (*RateLimitConfig, error) {
+	config := new(RateLimitConfig)
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(config); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("rate limit config: %w", err)
+	}
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+func (c *RateLimitConfig) validate() error {
+	var errs []error
+	check := func(name string, l RateLimit) {
+		if l.Rate < 0 || l.Burst < 0 {
+			errs = append(errs, fmt.Errorf("rate limit config: %s rate and burst must not be negative", name))
+		}
+	}
+	check("global", c.Global)
+	check("per_client", c.PerClient)
+	if c.MaxClients < 0 {
+		errs = append(errs, errors.New("rate limit config: max_clients must not be negative"))
+	}
+	for method, l := range c.Methods {
+		check(method, l)
+	}
+	return errors.Join(errs...)
+}
+
+func (c RateLimitConfig) maxClients() int {
+	if c.MaxClients <= 0 {
+		return defaultRateLimitMaxClients
+	}
+	return c.MaxClients
+}
+
+// equal 除 ClientKey 外的配置是否相同，MaxClients 按生效的值比较
+func (c RateLimitConfig) equal(other RateLimitConfig) bool {
+	c.ClientKey, other.ClientKey = nil, nil
+	c.MaxClients, other.MaxClients = c.maxClients(), other.maxClients()
+	return reflect.DeepEqual(c, other)
+}
+
+type clientRateLimiter struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// RateLimiter 服务端令牌桶限流，配置可在运行时更新
+type RateLimiter struct {
+	mu      sync.Mutex
+	config  RateLimitConfig
+	global  *rate.Limiter
+	methods map[string]*rate.Limiter
+	clients map[string]*clientRateLimiter
+	// overflow 客户端数超过 MaxClients 时共用的令牌桶
+	overflow *rate.Limiter
+	sweep    time.Time
+}
+
+// NewRateLimiter 创建限流器，ClientKey 为 nil 时使用 PeerIPClientKey
+func NewRateLimiter(config RateLimitConfig) *RateLimiter {
+	r := &RateLimiter{
+		methods: make(map[string]*rate.Limiter),
+		clients: make(map[string]*clientRateLimiter),
+	}
+	r.Update(config)
+	return r
+}
+
+// Update 更新限流配置，已有的令牌桶保留当前令牌数
+func (r *RateLimiter) Update(config RateLimitConfig) {
+	if config.ClientKey == nil {
+		config.ClientKey = PeerIPClientKey
+	}
+	config.MaxClients = config.maxClients()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.config = config
+	r.global = updateLimiter(r.global, config.Global)
+	methods := make(map[string]*rate.Limiter, len(config.Methods))
+	for pattern, limit := range config.Methods {
+		if l := updateLimiter(r.methods[pattern], limit); l != nil {
+			methods[pattern] = l
+		}
+	}
+	r.methods = methods
+	if !config.PerClient.enabled() {
+		r.clients = make(map[string]*clientRateLimiter)
+	}
+	for _, c := range r.clients {
+		c.limiter = updateLimiter(c.limiter, config.PerClient)
+	}
+	r.overflow = updateLimiter(r.overflow, config.PerClient)
+}
+
+// Config 当前限流配置
+func (r *RateLimiter) Config() RateLimitConfig {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.config
+}
+
+// clientCount 单独限流的客户端数
+func (r *RateLimiter) clientCount() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.clients)
+}
+
+func updateLimiter(l *rate.Limiter, limit RateLimit) *rate.Limiter {
+	if !limit.enabled() {
+		return nil
+	}
+	if l == nil {
+		return limit.newLimiter()
+	}
+	l.SetLimit(rate.Limit(limit.Rate))
+	l.SetBurst(limit.newLimiter().Burst())
+	return l
+}
+
+func (r *RateLimiter) methodLimiter(fullMethod string) *rate.Limiter {
+	l, _ := lookupMethod(r.methods, fullMethod)
+	return l
+}
+
+func (r *RateLimiter) clientLimiter(key string, now time.Time) *rate.Limiter {
+	if key == "" || !r.config.PerClient.enabled() {
+		return nil
+	}
+	c, ok := r.clients[key]
+	if !ok && (now.Sub(r.sweep) > rateLimitClientIdle || len(r.clients) >= r.config.MaxClients) {
+		// 定期或客户端数达到上限时回收空闲的限流器，达到上限时最多每秒回收一次
+		if now.Sub(r.sweep) > time.Second {
+			for k, c := range r.clients {
+				if now.Sub(c.lastSeen) > rateLimitClientIdle {
+					delete(r.clients, k)
+				}
+			}
+			r.sweep = now
+		}
+		if len(r.clients) >= r.config.MaxClients {
+			return r.overflow
+		}
+	}
+	if !ok {
+		c = &clientRateLimiter{limiter: r.config.PerClient.newLimiter()}
+		r.clients[key] = c
+	}
+	c.lastSeen = now
+	return c.limiter
+}
+
+// Allow 判断请求是否放行，不放行时返回需要等待的时间
+func (r *RateLimiter) Allow(ctx context.Context, fullMethod string) (retryAfter time.Duration, ok bool) {
+	now := time.Now()
+	r.mu.Lock()
+	clientKey := r.config.ClientKey
+	r.mu.Unlock()
+	key := clientKey(ctx)
+	r.mu.Lock()
+	limiters := []*rate.Limiter{r.global, r.methodLimiter(fullMethod), r.clientLimiter(key, now)}
+	r.mu.Unlock()
+
+	var reservations []*rate.Reservation
+	for _, l := range limiters {
+		if l == nil {
+			continue
+		}
+		res := l.ReserveN(now, 1)
+		reservations = append(reservations, res)
+		if !res.OK() {
+			retryAfter = time.Second
+			continue
+		}
+		if delay := res.DelayFrom(now); delay > retryAfter {
+			retryAfter = delay
+		}
+	}
+	if retryAfter == 0 {
+		return 0, true
+	}
+	for _, res := range reservations {
+		res.CancelAt(now)
+	}
+	return retryAfter, false
+}
+
+// resourceExhausted 带 RetryInfo 详情的限流错误
+func resourceExhausted(msg string, retryAfter time.Duration) error {
+	st := status.New(codes.ResourceExhausted, msg)
+	if withDetails, err := st.WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(retryAfter)}); err == nil {
+		st = withDetails
+	}
+	return st.Err()
+}
+
+// UnaryServerInterceptor 限流一元拦截器
+func (r *RateLimiter) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+		if retryAfter, ok := r.Allow(ctx, info.FullMethod); !ok {
+			return nil, resourceExhausted("rate limit exceeded", retryAfter)
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor 限流流拦截器，仅在建立流时限流
+func (r *RateLimiter) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if retryAfter, ok := r.Allow(stream.Context(), info.FullMethod); !ok {
+			return resourceExhausted("rate limit exceeded", retryAfter)
+		}
+		return handler(srv, stream)
+	}
+}
+
+// WithServerRateLimiter 启用限流，运行时通过 RateLimiter.Update 调整
+func WithServerRateLimiter(limiter *RateLimiter) ServerOption {
+	return func(o *ServerOptions) {
+		o.RateLimiter = limiter
+		o.unaryInterceptors = append(o.unaryInterceptors, limiter.UnaryServerInterceptor())
+		o.streamInterceptors = append(o.streamInterceptors, limiter.StreamServerInterceptor())
+	}
+}
+
+// WatchRateLimit 监听限流配置的变化并更新限流器，ClientKey 保持不变，解析失败时保留原配置，阻塞直到 ctx 取消；
+// 需要已通过 WithServerRateLimiter 启用限流
+func (s *GrpcServer) WatchRateLimit(ctx context.Context, source ConfigSource) error {
+	limiter := s.RateLimiter()
+	if limiter == nil {
+		return fmt.Errorf("%s grpc server: rate limiter is not enabled", s.opts.Name)
+	}
+	return source.Watch(ctx, func(data []byte, err error) {
+		var config *RateLimitConfig
+		if err == nil {
+			if config, err = ParseRateLimitConfig(data); err == nil {
+				current := limiter.Config()
+				config.ClientKey = current.ClientKey
+				if !current.equal(*config) {
+					limiter.Update(*config)
+					s.opts.Log.Infof(ctx, "%s rate limit updated", s.opts.Name)
+				}
+				return
+			}
+		}
+		s.opts.Log.Errorf(ctx, "%s rate limit reload failed, keeping previous config: %v", s.opts.Name, err)
+	})
+}
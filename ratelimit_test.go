@@ -0,0 +1,122 @@
+package ngrpc
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"google.golang.org/grpc/peer"
+)
+
+// slowLimit 测试期间不会补充令牌的令牌桶
+func slowLimit(burst int) RateLimit {
+	return RateLimit{Rate: 0.001, Burst: burst}
+}
+
+func peerContext(ip string) context.Context {
+	return peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP(ip), Port: 1234}})
+}
+
+// allowed 连续调用 n 次，返回放行次数
+func allowed(limiter *RateLimiter, ctx context.Context, method string, n int) int {
+	count := 0
+	for range n {
+		if _, ok := limiter.Allow(ctx, method); ok {
+			count++
+		}
+	}
+	return count
+}
+
+func TestRateLimiterBuckets(t *testing.T) {
+	limiter := NewRateLimiter(RateLimitConfig{
+		Global: slowLimit(10),
+		Methods: map[string]RateLimit{
+			"/pkg.Service/*":    slowLimit(5),
+			"/pkg.Service/Slow": slowLimit(1),
+		},
+		PerClient: slowLimit(3),
+	})
+	a := peerContext("10.0.0.1")
+	if got := allowed(limiter, a, "/pkg.Service/Slow", 3); got != 1 {
+		t.Fatalf("exact method allowed %d, want 1", got)
+	}
+	if got := allowed(limiter, a, "/pkg.Service/Fast", 3); got != 2 {
+		t.Fatalf("client a allowed %d on wildcard method, want 2 left in its bucket", got)
+	}
+	b := peerContext("10.0.0.2")
+	if got := allowed(limiter, b, "/pkg.Service/Fast", 3); got != 3 {
+		t.Fatalf("client b allowed %d, want 3 left in the wildcard bucket", got)
+	}
+	retryAfter, ok := limiter.Allow(peerContext("10.0.0.3"), "/pkg.Service/Fast")
+	if ok || retryAfter <= 0 {
+		t.Fatalf("Allow() = %s, %v, want rejected with retry delay", retryAfter, ok)
+	}
+	// 被拒绝的请求不消耗其他令牌桶
+	c := peerContext("10.0.0.3")
+	if got := allowed(limiter, c, "/other.Service/Call", 5); got != 3 {
+		t.Fatalf("client c allowed %d, want 3", got)
+	}
+	if got := allowed(limiter, peerContext("10.0.0.4"), "/other.Service/Call", 5); got != 1 {
+		t.Fatalf("client d allowed %d, want the 1 remaining global token", got)
+	}
+}
+
+func TestRateLimiterMaxClients(t *testing.T) {
+	limiter := NewRateLimiter(RateLimitConfig{PerClient: slowLimit(2), MaxClients: 2})
+	for _, ip := range []string{"10.0.0.1", "10.0.0.2"} {
+		if got := allowed(limiter, peerContext(ip), "/pkg.Service/Call", 3); got != 2 {
+			t.Fatalf("client %s allowed %d, want 2", ip, got)
+		}
+	}
+	// 超出上限的客户端共用一个令牌桶
+	if got := allowed(limiter, peerContext("10.0.0.3"), "/pkg.Service/Call", 1); got != 1 {
+		t.Fatalf("first overflow client allowed %d, want 1", got)
+	}
+	if got := allowed(limiter, peerContext("10.0.0.4"), "/pkg.Service/Call", 3); got != 1 {
+		t.Fatalf("second overflow client allowed %d, want the 1 remaining overflow token", got)
+	}
+	if n := len(limiter.clients); n != 2 {
+		t.Fatalf("tracked %d clients, want 2", n)
+	}
+	// 没有客户端标识时不按客户端限流
+	if got := allowed(limiter, context.Background(), "/pkg.Service/Call", 5); got != 5 {
+		t.Fatalf("anonymous calls allowed %d, want 5", got)
+	}
+}
+
+// staticSource 依次推送给定配置的配置来源
+type staticSource [][]byte
+
+func (s staticSource) Watch(ctx context.Context, fn func(data []byte, err error)) error {
+	for _, data := range s {
+		fn(data, nil)
+	}
+	return nil
+}
+
+func TestWatchRateLimit(t *testing.T) {
+	clientKey := MetadataClientKey(APIKeyHeader)
+	limiter := NewRateLimiter(RateLimitConfig{PerClient: slowLimit(1), ClientKey: clientKey})
+	server := NewGrpcServer(context.Background(), WithServerLogger(nopLogger{}), WithServerRateLimiter(limiter))
+	source := staticSource{
+		[]byte("per_client: {rate: 0.001, burst: 1}\nmax_clients: 1\n"),
+		[]byte("per_client: {rate: -1}\n"),
+	}
+	if err := server.WatchRateLimit(context.Background(), source); err != nil {
+		t.Fatal(err)
+	}
+	config := limiter.Config()
+	if config.MaxClients != 1 {
+		t.Fatalf("max clients = %d, want 1 after a change to max_clients only", config.MaxClients)
+	}
+	if config.ClientKey == nil {
+		t.Fatal("client key was dropped")
+	}
+	if !config.equal(RateLimitConfig{PerClient: slowLimit(1), MaxClients: 1}) {
+		t.Fatalf("config = %+v, invalid config was applied", config)
+	}
+	if !(RateLimitConfig{}).equal(RateLimitConfig{MaxClients: defaultRateLimitMaxClients}) {
+		t.Fatal("zero max clients should equal the default")
+	}
+}
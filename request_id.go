@@ -0,0 +1,99 @@
+package ngrpc
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// RequestIDHeader 请求ID的metadata键
+const RequestIDHeader = "x-request-id"
+
+// newRequestID 生成随机请求ID
+func newRequestID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// RequestIDContextHandler 从请求metadata读取请求ID，不存在时生成，放入上下文并写入响应头
+func RequestIDContextHandler(ctx context.Context) context.Context {
+	var requestID string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(RequestIDHeader); len(values) > 0 {
+			requestID = values[0]
+		}
+	}
+	if requestID == "" {
+		requestID = newRequestID()
+	}
+	_ = grpc.SetHeader(ctx, metadata.Pairs(RequestIDHeader, requestID))
+	return ContextWithRequestID(ctx, requestID)
+}
+
+// WithServerRequestID 启用请求ID拦截器
+func WithServerRequestID() ServerOption {
+	return func(o *ServerOptions) {
+		o.unaryInterceptors = append(o.unaryInterceptors, UnaryServerInterceptor(RequestIDContextHandler))
+		o.streamInterceptors = append(o.streamInterceptors, StreamServerInterceptor(RequestIDContextHandler))
+	}
+}
+
+// forwardMetadata 将上下文中的请求ID和允许的入站metadata追加到出站metadata
+func forwardMetadata(ctx context.Context, keys []string) context.Context {
+	outgoing, _ := metadata.FromOutgoingContext(ctx)
+	var pairs []string
+	if requestID, ok := RequestIDFromContext(ctx); ok && requestID != "" && len(outgoing.Get(RequestIDHeader)) == 0 {
+		pairs = append(pairs, RequestIDHeader, requestID)
+	}
+	if incoming, ok := metadata.FromIncomingContext(ctx); ok {
+		for _, key := range keys {
+			if len(outgoing.Get(key)) > 0 {
+				continue
+			}
+			for _, value := range incoming.Get(key) {
+				pairs = append(pairs, key, value)
+			}
+		}
+	}
+	if len(pairs) == 0 {
+		return ctx
+	}
+	return metadata.AppendToOutgoingContext(ctx, pairs...)
+}
+
+// ForwardMetadataUnaryClientInterceptor 转发请求ID以及 keys 指定的入站metadata
+func ForwardMetadataUnaryClientInterceptor(keys ...string) grpc.UnaryClientInterceptor {
+	keys = lowerKeys(keys)
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		return invoker(forwardMetadata(ctx, keys), method, req, reply, cc, opts...)
+	}
+}
+
+// ForwardMetadataStreamClientInterceptor 转发请求ID以及 keys 指定的入站metadata
+func ForwardMetadataStreamClientInterceptor(keys ...string) grpc.StreamClientInterceptor {
+	keys = lowerKeys(keys)
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		return streamer(forwardMetadata(ctx, keys), desc, cc, method, opts...)
+	}
+}
+
+// WithClientForwardMetadata 调用时转发请求ID以及 keys 指定的入站metadata，如 x-tenant-id、x-locale
+func WithClientForwardMetadata(keys ...string) ClientOption {
+	return func(o *ClientOptions) {
+		o.unaryInterceptors = append(o.unaryInterceptors, ForwardMetadataUnaryClientInterceptor(keys...))
+		o.streamInterceptors = append(o.streamInterceptors, ForwardMetadataStreamClientInterceptor(keys...))
+	}
+}
+
+func lowerKeys(keys []string) []string {
+	lowered := make([]string, len(keys))
+	for i, key := range keys {
+		lowered[i] = strings.ToLower(key)
+	}
+	return lowered
+}
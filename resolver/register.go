@@ -4,6 +4,7 @@ import (
 	"context"
 	"path"
 	"strings"
+	"sync"
 	"time"
 
 	clientv3 "go.etcd.io/etcd/client/v3"
@@ -32,7 +33,8 @@ type Registry interface {
 type EtcdRegistry struct {
 	etcdClient *clientv3.Client
 	domain     string
-	leaseID    clientv3.LeaseID
+	mu         sync.Mutex
+	leaseIDs   []clientv3.LeaseID
 	ctx        context.Context
 	cancel     context.CancelFunc
 }
@@ -47,6 +49,11 @@ func NewEtcdRegistry(ctx context.Context, etcdClient *clientv3.Client, domain st
 	}
 }
 
+// Domain 注册域
+func (e *EtcdRegistry) Domain() string {
+	return e.domain
+}
+
 func (e *EtcdRegistry) Register(serviceInfo *ServiceInfo) (err error) {
 	// 创建租约
 	var lease *clientv3.LeaseGrantResponse
@@ -54,7 +61,11 @@ func (e *EtcdRegistry) Register(serviceInfo *ServiceInfo) (err error) {
 	if err != nil {
 		return
 	}
-	e.leaseID = lease.ID
+	leaseID := lease.ID
+	// 每个地址使用独立的租约，Close 时全部撤销
+	e.mu.Lock()
+	e.leaseIDs = append(e.leaseIDs, leaseID)
+	e.mu.Unlock()
 	// 绑定租约
 	target := path.Join(e.domain, serviceInfo.Name)
 	var em endpoints.Manager
@@ -62,7 +73,8 @@ func (e *EtcdRegistry) Register(serviceInfo *ServiceInfo) (err error) {
 	if err != nil {
 		return
 	}
-	key := path.Join(target, serviceInfo.Address)
+	// 地址可能包含 unix:///，不能用 path.Join 清理
+	key := target + "/" + serviceInfo.Address
 	endpoint := endpoints.Endpoint{
 		Addr: serviceInfo.Address,
 		Metadata: map[string]string{
@@ -70,13 +82,13 @@ func (e *EtcdRegistry) Register(serviceInfo *ServiceInfo) (err error) {
 			"tags": strings.Join(serviceInfo.Tags, ","),
 		},
 	}
-	err = em.AddEndpoint(e.ctx, key, endpoint, clientv3.WithLease(e.leaseID))
+	err = em.AddEndpoint(e.ctx, key, endpoint, clientv3.WithLease(leaseID))
 	if err != nil {
 		return
 	}
 	// 续租 发送心跳，表明服务正常
 	var keepAliveChan <-chan *clientv3.LeaseKeepAliveResponse
-	keepAliveChan, err = e.etcdClient.KeepAlive(e.ctx, e.leaseID)
+	keepAliveChan, err = e.etcdClient.KeepAlive(e.ctx, leaseID)
 	if err != nil {
 		return
 	}
@@ -98,9 +110,26 @@ func (e *EtcdRegistry) watcher(resChan <-chan *clientv3.LeaseKeepAliveResponse)
 	}
 }
 
+// LeaseIDs 已注册地址的租约
+func (e *EtcdRegistry) LeaseIDs() []clientv3.LeaseID {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]clientv3.LeaseID(nil), e.leaseIDs...)
+}
+
 func (e *EtcdRegistry) Close() (err error) {
 	e.cancel()
-	// 撤销租约
-	e.etcdClient.Revoke(e.ctx, e.leaseID)
+	// 撤销租约，e.ctx 已取消，使用新的上下文
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	e.mu.Lock()
+	leaseIDs := e.leaseIDs
+	e.leaseIDs = nil
+	e.mu.Unlock()
+	for _, leaseID := range leaseIDs {
+		if _, rerr := e.etcdClient.Revoke(ctx, leaseID); rerr != nil && err == nil {
+			err = rerr
+		}
+	}
 	return
 }
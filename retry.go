@@ -0,0 +1,261 @@
+package ngrpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// RetryPolicy 客户端一元调用重试策略
+type RetryPolicy struct {
+	// MaxAttempts 包括首次调用在内的最大尝试次数，2~5
+	MaxAttempts       int           `yaml:"max_attempts" json:"max_attempts"`
+	InitialBackoff    time.Duration `yaml:"initial_backoff" json:"initial_backoff"`
+	MaxBackoff        time.Duration `yaml:"max_backoff" json:"max_backoff"`
+	BackoffMultiplier float64       `yaml:"backoff_multiplier" json:"backoff_multiplier"`
+	// RetryableStatusCodes 可重试的状态码，如 UNAVAILABLE
+	RetryableStatusCodes []string `yaml:"retryable_status_codes" json:"retryable_status_codes"`
+	// Services 生效的服务全名，为空时对全部服务生效
+	Services []string `yaml:"services" json:"services"`
+	// ThrottleMaxTokens 重试限流的令牌桶容量，0~1000，默认10；失败的调用消耗1个令牌，
+	// 成功的调用返还 ThrottleTokenRatio 个，令牌不超过一半时不再重试
+	ThrottleMaxTokens float64 `yaml:"throttle_max_tokens" json:"throttle_max_tokens"`
+	// ThrottleTokenRatio 成功调用返还的令牌数，0~1，默认0.1
+	ThrottleTokenRatio float64 `yaml:"throttle_token_ratio" json:"throttle_token_ratio"`
+}
+
+const (
+	defaultRetryThrottleMaxTokens  = 10
+	defaultRetryThrottleTokenRatio = 0.1
+	// retryPushbackHeader 服务端要求的重试等待时间（毫秒），为负数或无法解析时不重试
+	retryPushbackHeader = "grpc-retry-pushback-ms"
+)
+
+// Validate 校验重试策略
+func (p *RetryPolicy) Validate() error {
+	var errs []error
+	if p.MaxAttempts < 2 || p.MaxAttempts > 5 {
+		errs = append(errs, fmt.Errorf("max_attempts must be between 2 and 5, got %d", p.MaxAttempts))
+	}
+	if p.InitialBackoff <= 0 {
+		errs = append(errs, errors.New("initial_backoff must be positive"))
+	}
+	if p.MaxBackoff < p.InitialBackoff {
+		errs = append(errs, errors.New("max_backoff must not be less than initial_backoff"))
+	}
+	if p.BackoffMultiplier <= 0 {
+		errs = append(errs, errors.New("backoff_multiplier must be positive"))
+	}
+	if len(p.RetryableStatusCodes) == 0 {
+		errs = append(errs, errors.New("retryable_status_codes must not be empty"))
+	}
+	if _, err := p.statusCodes(); err != nil {
+		errs = append(errs, err)
+	}
+	if p.ThrottleMaxTokens < 0 || p.ThrottleMaxTokens > 1000 {
+		errs = append(errs, fmt.Errorf("throttle_max_tokens must be between 0 and 1000, got %v", p.ThrottleMaxTokens))
+	}
+	if p.ThrottleTokenRatio < 0 || p.ThrottleTokenRatio > 1 {
+		errs = append(errs, fmt.Errorf("throttle_token_ratio must be between 0 and 1, got %v", p.ThrottleTokenRatio))
+	}
+	return errors.Join(errs...)
+}
+
+func (p *RetryPolicy) statusCodes() ([]codes.Code, error) {
+	var result []codes.Code
+	for _, name := range p.RetryableStatusCodes {
+		var code codes.Code
+		if err := code.UnmarshalJSON([]byte(strconv.Quote(name))); err != nil {
+			return nil, fmt.Errorf("retryable_status_codes: invalid code %q", name)
+		}
+		result = append(result, code)
+	}
+	return result, nil
+}
+
+// throttle 重试限流参数，未设置时使用默认值
+func (p *RetryPolicy) throttle() (maxTokens, tokenRatio float64) {
+	maxTokens, tokenRatio = p.ThrottleMaxTokens, p.ThrottleTokenRatio
+	if maxTokens == 0 {
+		maxTokens = defaultRetryThrottleMaxTokens
+	}
+	if tokenRatio == 0 {
+		tokenRatio = defaultRetryThrottleTokenRatio
+	}
+	return
+}
+
+// ServiceConfig 转换为 gRPC service config JSON，可通过 grpc.WithDefaultServiceConfig 使用 grpc-go 内置的重试
+func (p *RetryPolicy) ServiceConfig() (string, error) {
+	if err := p.Validate(); err != nil {
+		return "", err
+	}
+	statusCodes, _ := p.statusCodes()
+	type name struct {
+		Service string `json:"service"`
+	}
+	names := []name{{}}
+	if len(p.Services) > 0 {
+		names = names[:0]
+		for _, service := range p.Services {
+			names = append(names, name{Service: service})
+		}
+	}
+	maxTokens, tokenRatio := p.throttle()
+	config := map[string]interface{}{
+		"retryThrottling": map[string]interface{}{
+			"maxTokens":  maxTokens,
+			"tokenRatio": tokenRatio,
+		},
+		"methodConfig": []interface{}{
+			map[string]interface{}{
+				"name": names,
+				"retryPolicy": map[string]interface{}{
+					"maxAttempts":          p.MaxAttempts,
+					"initialBackoff":       formatProtoDuration(p.InitialBackoff),
+					"maxBackoff":           formatProtoDuration(p.MaxBackoff),
+					"backoffMultiplier":    p.BackoffMultiplier,
+					"retryableStatusCodes": statusCodes,
+				},
+			},
+		},
+	}
+	data, err := json.Marshal(config)
+	return string(data), err
+}
+
+// formatProtoDuration google.protobuf.Duration 的 JSON 格式，如 0.1s
+func formatProtoDuration(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', -1, 64) + "s"
+}
+
+// WithClientRetryPolicy 设置一元调用的重试策略，可通过 GrpcClient.SetRetryPolicy 在运行时替换
+func WithClientRetryPolicy(policy RetryPolicy) ClientOption {
+	return func(o *ClientOptions) {
+		o.RetryPolicy = &policy
+	}
+}
+
+// retryPolicy 生效中的重试策略
+type retryPolicy struct {
+	RetryPolicy
+	codes    map[codes.Code]bool
+	services map[string]bool
+
+	mu         sync.Mutex
+	maxTokens  float64
+	tokenRatio float64
+	tokens     float64
+}
+
+// SetRetryPolicy 运行时替换重试策略，nil 表示不重试；重试限流的令牌随之重置
+func (c *GrpcClient) SetRetryPolicy(policy *RetryPolicy) error {
+	if policy == nil {
+		c.retry.Store(nil)
+		return nil
+	}
+	if err := policy.Validate(); err != nil {
+		return fmt.Errorf("retry policy: %w", err)
+	}
+	p := &retryPolicy{RetryPolicy: *policy, codes: make(map[codes.Code]bool), services: make(map[string]bool)}
+	statusCodes, _ := policy.statusCodes()
+	for _, code := range statusCodes {
+		p.codes[code] = true
+	}
+	for _, service := range policy.Services {
+		p.services[service] = true
+	}
+	p.maxTokens, p.tokenRatio = policy.throttle()
+	p.tokens = p.maxTokens
+	c.retry.Store(p)
+	return nil
+}
+
+// RetryPolicy 当前重试策略，未设置时为nil
+func (c *GrpcClient) RetryPolicy() *RetryPolicy {
+	if p := c.retry.Load(); p != nil {
+		policy := p.RetryPolicy
+		return &policy
+	}
+	return nil
+}
+
+func (p *retryPolicy) matches(fullMethod string) bool {
+	if len(p.services) == 0 {
+		return true
+	}
+	service, _, _ := strings.Cut(strings.TrimPrefix(fullMethod, "/"), "/")
+	return p.services[service]
+}
+
+// throttle 记录调用结果，返回是否允许重试
+func (p *retryPolicy) throttle(err error) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err == nil {
+		p.tokens = min(p.tokens+p.tokenRatio, p.maxTokens)
+		return false
+	}
+	if !p.codes[status.Code(err)] {
+		return false
+	}
+	p.tokens = max(p.tokens-1, 0)
+	return p.tokens > p.maxTokens/2
+}
+
+// retryPushback 解析服务端的 grpc-retry-pushback-ms，ok 为 false 时表示服务端不允许重试
+func retryPushback(trailer metadata.MD) (delay time.Duration, set, ok bool) {
+	values := trailer.Get(retryPushbackHeader)
+	if len(values) == 0 {
+		return 0, false, true
+	}
+	ms, err := strconv.ParseInt(values[0], 10, 64)
+	if err != nil || ms < 0 {
+		return 0, true, false
+	}
+	return time.Duration(ms) * time.Millisecond, true, true
+}
+
+// retryUnaryClientInterceptor 按重试策略重试一元调用，退避时间在 [0, backoff) 内随机；
+// 服务端返回 grpc-retry-pushback-ms 时按其等待并重置退避时间，失败过多时停止重试
+func (c *GrpcClient) retryUnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		policy := c.retry.Load()
+		if policy == nil || !policy.matches(method) {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+		backoff := policy.InitialBackoff
+		for attempt := 1; ; attempt++ {
+			var trailer metadata.MD
+			err := invoker(ctx, method, req, reply, cc, append(opts, grpc.Trailer(&trailer))...)
+			if !policy.throttle(err) || attempt >= policy.MaxAttempts {
+				return err
+			}
+			delay, pushback, ok := retryPushback(trailer)
+			if !ok {
+				return err
+			}
+			if pushback {
+				backoff = policy.InitialBackoff
+			} else {
+				delay = rand.N(backoff)
+				// multiplier 小于1时 backoff 会不断减小，rand.N 要求参数为正数
+				backoff = max(min(time.Duration(float64(backoff)*policy.BackoffMultiplier), policy.MaxBackoff), 1)
+			}
+			if sleepContext(ctx, delay) != nil {
+				return err
+			}
+		}
+	}
+}
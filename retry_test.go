@@ -0,0 +1,144 @@
+package ngrpc
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// failingInterceptor 前 failures 次调用返回 code，trailer 不为空时随错误返回
+func failingInterceptor(calls *atomic.Int32, failures int32, code codes.Code, trailer metadata.MD) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if calls.Add(1) <= failures {
+			if trailer != nil {
+				grpc.SetTrailer(ctx, trailer)
+			}
+			return nil, status.Error(code, "injected")
+		}
+		return handler(ctx, req)
+	}
+}
+
+func newRetryTestClient(t *testing.T, server *GrpcServer, policy RetryPolicy) *GrpcClient {
+	t.Helper()
+	client := NewGrpcClient(context.Background(),
+		WithClientAddress(server.Addr().String()),
+		WithClientLogger(nopLogger{}),
+		WithClientRetryPolicy(policy),
+	)
+	t.Cleanup(func() { client.close() })
+	return client
+}
+
+func testRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:          3,
+		InitialBackoff:       time.Millisecond,
+		MaxBackoff:           10 * time.Millisecond,
+		BackoffMultiplier:    2,
+		RetryableStatusCodes: []string{"UNAVAILABLE"},
+	}
+}
+
+func TestRetryUnary(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		failures int32
+		code     codes.Code
+		trailer  metadata.MD
+		wantCode codes.Code
+		want     int32
+	}{
+		{"retried until success", 2, codes.Unavailable, nil, codes.OK, 3},
+		{"max attempts", 5, codes.Unavailable, nil, codes.Unavailable, 3},
+		{"not retryable", 5, codes.InvalidArgument, nil, codes.InvalidArgument, 1},
+		{"pushback", 1, codes.Unavailable, metadata.Pairs(retryPushbackHeader, "5"), codes.OK, 2},
+		{"negative pushback", 1, codes.Unavailable, metadata.Pairs(retryPushbackHeader, "-1"), codes.Unavailable, 1},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := new(atomic.Int32)
+			server := startEchoServer(t, WithServerUnaryServerInterceptors(failingInterceptor(calls, tt.failures, tt.code, tt.trailer)))
+			client := newRetryTestClient(t, server, testRetryPolicy())
+			err := client.GetConn().Invoke(context.Background(), echoUnary, wrapperspb.String("retry"), new(wrapperspb.StringValue))
+			if status.Code(err) != tt.wantCode {
+				t.Fatalf("err = %v, want %s", err, tt.wantCode)
+			}
+			if got := calls.Load(); got != tt.want {
+				t.Fatalf("attempts = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryShrinkingBackoff(t *testing.T) {
+	calls := new(atomic.Int32)
+	server := startEchoServer(t, WithServerUnaryServerInterceptors(failingInterceptor(calls, 5, codes.Unavailable, nil)))
+	policy := testRetryPolicy()
+	policy.InitialBackoff, policy.MaxBackoff, policy.BackoffMultiplier = 1, 1, 0.5
+	client := newRetryTestClient(t, server, policy)
+	// backoff 减小到0时不应 panic
+	err := client.GetConn().Invoke(context.Background(), echoUnary, wrapperspb.String("retry"), new(wrapperspb.StringValue))
+	if status.Code(err) != codes.Unavailable || calls.Load() != 3 {
+		t.Fatalf("err = %v, attempts = %d", err, calls.Load())
+	}
+}
+
+func TestRetryThrottle(t *testing.T) {
+	policy := testRetryPolicy()
+	policy.ThrottleMaxTokens = 4
+	policy.ThrottleTokenRatio = 0.5
+	client := &GrpcClient{}
+	if err := client.SetRetryPolicy(&policy); err != nil {
+		t.Fatal(err)
+	}
+	p := client.retry.Load()
+	unavailable := status.Error(codes.Unavailable, "down")
+	// 4 个令牌：失败后剩余3个仍可重试，剩余2个（不超过一半）时停止
+	if !p.throttle(unavailable) {
+		t.Fatal("retry throttled with 3 tokens left")
+	}
+	if p.throttle(unavailable) {
+		t.Fatal("retry allowed with 2 tokens left")
+	}
+	if p.throttle(status.Error(codes.InvalidArgument, "bad")) {
+		t.Fatal("non-retryable code retried")
+	}
+	// 成功的调用返还令牌：2 + 3*0.5，失败后剩余2.5个
+	for i := 0; i < 3; i++ {
+		p.throttle(nil)
+	}
+	if !p.throttle(unavailable) {
+		t.Fatal("retry throttled after successes")
+	}
+}
+
+func TestRetryPolicyValidate(t *testing.T) {
+	policy := RetryPolicy{
+		MaxAttempts:          6,
+		InitialBackoff:       time.Second,
+		MaxBackoff:           time.Millisecond,
+		RetryableStatusCodes: []string{"NOPE"},
+		ThrottleTokenRatio:   2,
+	}
+	err := policy.Validate()
+	if err == nil {
+		t.Fatal("invalid policy accepted")
+	}
+	for _, want := range []string{"max_attempts", "max_backoff", "backoff_multiplier", `invalid code "NOPE"`, "throttle_token_ratio"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("err = %v, want %q", err, want)
+		}
+	}
+	valid := testRetryPolicy()
+	if err = valid.Validate(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -2,11 +2,17 @@ package ngrpc
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/nilorg/ngrpc/v2/resolver"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
 	"google.golang.org/grpc/reflection"
 )
 
@@ -15,6 +21,31 @@ type GrpcServer struct {
 	server *grpc.Server
 	opts   ServerOptions
 	ctx    context.Context
+	// serviceInfo 当前实例信息，Run之后可用
+	serviceInfo *resolver.ServiceInfo
+	mu          sync.Mutex
+	httpServers []*http.Server
+	gatewayConn *grpc.ClientConn
+	adminServer *grpc.Server
+	health      *health.Server
+	// registered 已注册到注册中心的实例
+	registered []*resolver.ServiceInfo
+	startedAt  time.Time
+	// allowedMethods 允许调用的方法，nil 表示全部允许
+	allowedMethods atomic.Pointer[[]string]
+	deregisterOnce sync.Once
+	deregisterErr  error
+	// running Run 或 Start 已调用
+	running atomic.Bool
+	// ready 开始监听后关闭
+	ready     chan struct{}
+	readyOnce sync.Once
+	addr      net.Addr
+	// done Run 返回后关闭，err 为 Run 的返回值
+	done chan struct{}
+	err  error
+	// optionsErr 参数校验错误，Run 时返回
+	optionsErr error
 }
 
 // GetSrv 获取rpc server
@@ -22,80 +53,284 @@ func (s *GrpcServer) GetSrv() *grpc.Server {
 	return s.server
 }
 
+// RateLimiter 获取限流器，未启用限流时为nil
+func (s *GrpcServer) RateLimiter() *RateLimiter {
+	return s.opts.RateLimiter
+}
+
 func (s *GrpcServer) register() {
-	// 在gRPC服务器上注册反射服务。
-	reflection.Register(s.server)
+	if s.opts.Reflection {
+		// 在gRPC服务器上注册反射服务。
+		reflection.Register(s.server)
+	}
+}
+
+// Run 监听并阻塞提供服务，任一监听失败时关闭全部监听并返回错误；只能调用一次
+func (s *GrpcServer) Run() error {
+	if err := s.begin(); err != nil {
+		return err
+	}
+	return s.end(s.run())
 }
 
-func (s *GrpcServer) Run() {
+// begin 标记服务已运行，重复运行时返回错误
+func (s *GrpcServer) begin() error {
+	if !s.running.CompareAndSwap(false, true) {
+		return fmt.Errorf("%s grpc server is already running", s.opts.Name)
+	}
+	return nil
+}
+
+// end 记录 Run 的返回值并关闭 done
+func (s *GrpcServer) end(err error) error {
+	s.err = err
+	close(s.done)
+	return err
+}
+
+func (s *GrpcServer) run() error {
+	if s.optionsErr != nil {
+		s.opts.Log.Errorf(s.ctx, "%v", s.optionsErr)
+		return s.optionsErr
+	}
 	s.register()
-	var address string
-	if s.opts.RandomPort {
-		address = ":0"
-	} else {
-		address = s.opts.Address
+	listeners, err := s.listen()
+	if err != nil {
+		s.opts.Log.Errorf(s.ctx, "%s grpc server failed to listen: %v", s.opts.Name, err)
+		return err
 	}
-	lis, err := net.Listen("tcp", address)
+	gatewayListeners, err := s.gatewayListeners()
 	if err != nil {
-		s.opts.Log.Fatalf(s.ctx, "%s grpc server failed to listen: %v", s.opts.Name, err)
-		return
+		for _, lis := range listeners {
+			lis.Close()
+		}
+		s.opts.Log.Errorf(s.ctx, "%s grpc server failed to start gateway: %v", s.opts.Name, err)
+		return err
 	}
-	if s.opts.register != nil {
+	listeners = append(listeners, gatewayListeners...)
+	adminListeners, err := s.adminListeners()
+	if err != nil {
+		for _, lis := range listeners {
+			lis.Close()
+		}
+		s.closeGateway()
+		s.opts.Log.Errorf(s.ctx, "%s grpc server failed to start admin: %v", s.opts.Name, err)
+		return err
+	}
+	listeners = append(listeners, adminListeners...)
+	var serviceInfos []*resolver.ServiceInfo
+	for _, lis := range listeners {
 		serviceInfo := resolver.NewServiceInfo()
 		serviceInfo.Name = s.opts.Name
-		if s.opts.RandomPort {
-			ipAddr, err := LocalIPv4()
-			if err != nil {
-				s.opts.Log.Fatalf(s.ctx, "LocalIPv4: %s", err)
-				return
+		serviceInfo.Address = lis.advertise
+		serviceInfos = append(serviceInfos, serviceInfo)
+	}
+	s.serviceInfo = serviceInfos[0]
+	if s.opts.register != nil {
+		for i, serviceInfo := range serviceInfos {
+			if !listeners[i].registrable() {
+				continue
 			}
-			port := lis.Addr().(*net.TCPAddr).Port
-			serviceInfo.Address = fmt.Sprintf("%s:%d", ipAddr, port)
-		} else {
-			serviceInfo.Address = address
+			if err = s.opts.register.Register(serviceInfo); err != nil {
+				for _, lis := range listeners {
+					lis.Close()
+				}
+				s.closeGateway()
+				s.closeAdmin()
+				s.opts.Log.Errorf(s.ctx, "%s grpc server failed to register %s: %v", s.opts.Name, serviceInfo.Address, err)
+				// 注销已注册的实例
+				if derr := s.Deregister(); derr != nil {
+					s.opts.Log.Errorf(s.ctx, "%s grpc server failed to unregister: %v", s.opts.Name, derr)
+				}
+				return err
+			}
+			s.mu.Lock()
+			s.registered = append(s.registered, serviceInfo)
+			s.mu.Unlock()
 		}
-		err = s.opts.register.Register(serviceInfo)
-		if err != nil {
-			s.opts.Log.Fatalf(s.ctx, "%s grpc server failed to register: %v", s.opts.Name, err)
+	}
+	errs := make(chan error, len(listeners))
+	for _, lis := range listeners {
+		go func(lis *serverListener) {
+			if err := s.serve(lis); err != nil {
+				errs <- fmt.Errorf("serve %s: %w", lis.Addr(), err)
+				return
+			}
+			errs <- nil
+		}(lis)
+	}
+	s.mu.Lock()
+	s.startedAt = time.Now()
+	s.addr = listeners[0].Addr()
+	s.mu.Unlock()
+	s.setServing(true)
+	s.readyOnce.Do(func() {
+		close(s.ready)
+	})
+	// 任一监听出错时停止全部监听
+	for range listeners {
+		if err = <-errs; err != nil {
+			s.opts.Log.Errorf(s.ctx, "%s grpc server failed to serve: %v", s.opts.Name, err)
+			s.server.Stop()
+			s.closeHTTPServers()
+			s.closeGateway()
+			s.closeAdmin()
+			return err
 		}
 	}
-	if err := s.server.Serve(lis); err != nil {
-		s.opts.Log.Fatalf(s.ctx, "%s grpc server failed to serve: %v", s.opts.Name, err)
+	return nil
+}
+
+// serve 在 listener 上提供服务
+func (s *GrpcServer) serve(lis *serverListener) error {
+	switch {
+	case lis.handler != nil:
+		return serveHTTP(s.newHTTPServer(lis.handler, nil), lis)
+	case (s.opts.HTTPHandler != nil || s.opts.Web != nil) && !lis.internal:
+		return serveHTTP(s.newHTTPServer(s.httpHandler(), s.opts.HTTPTLSConfig), lis)
+	}
+	// Serve 开始之前已停止时同样视为正常停止
+	if err := s.server.Serve(lis); !errors.Is(err, grpc.ErrServerStopped) {
+		return err
 	}
+	return nil
+}
+
+// Addr 主监听实际绑定的地址，Ready 之前为nil；WithServerRandomPort 时可用于获取端口
+func (s *GrpcServer) Addr() net.Addr {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.addr
+}
+
+// Ready 全部监听已打开并完成注册后关闭；Run 在此之前失败时不会关闭，等待时应同时等待 Done
+func (s *GrpcServer) Ready() <-chan struct{} {
+	return s.ready
+}
+
+// Done Run 返回后关闭，之后可通过 Err 获取返回的错误
+func (s *GrpcServer) Done() <-chan struct{} {
+	return s.done
 }
 
-func (s *GrpcServer) Start() {
+// Err Run 返回的错误，Done 关闭之前或正常停止时为nil
+func (s *GrpcServer) Err() error {
+	select {
+	case <-s.done:
+		return s.err
+	default:
+		return nil
+	}
+}
+
+// Start 在后台运行服务，监听或注册失败时返回错误，开始监听后返回nil；
+// 之后的服务错误通过 Done 和 Err 获取
+func (s *GrpcServer) Start() error {
+	if err := s.begin(); err != nil {
+		return err
+	}
 	go func() {
-		s.Run()
+		s.end(s.run())
 	}()
+	select {
+	case <-s.ready:
+		return nil
+	case <-s.done:
+		return s.err
+	}
 }
 
 func (s *GrpcServer) Stop() {
+	s.health.Shutdown()
 	if s.server == nil {
 		s.opts.Log.Warnf(s.ctx, "stop %s grpc server is nil", s.opts.Name)
 	} else {
 		s.server.Stop()
 	}
-	if s.opts.register != nil {
-		err := s.opts.register.Close()
-		if err != nil {
-			s.opts.Log.Errorf(s.ctx, "%s grpc server failed to unregister: %v", s.opts.Name, err)
-		}
+	s.closeHTTPServers()
+	s.closeGateway()
+	s.closeAdmin()
+	if err := s.Deregister(); err != nil {
+		s.opts.Log.Errorf(s.ctx, "%s grpc server failed to unregister: %v", s.opts.Name, err)
 	}
 }
 
+// Deregister 从注册中心注销并关闭注册中心，可重复调用
+func (s *GrpcServer) Deregister() error {
+	if s.opts.register == nil {
+		return nil
+	}
+	s.deregisterOnce.Do(func() {
+		s.deregisterErr = s.opts.register.Close()
+	})
+	return s.deregisterErr
+}
+
+// GracefulStop 停止接受新连接并等待进行中的调用完成，ctx 到期后强制停止；不注销注册中心
+func (s *GrpcServer) GracefulStop(ctx context.Context) error {
+	s.health.Shutdown()
+	done := make(chan struct{})
+	go func() {
+		s.server.GracefulStop()
+		close(done)
+	}()
+	errs := []error{s.shutdownHTTPServers(ctx)}
+	select {
+	case <-done:
+	case <-ctx.Done():
+		s.server.Stop()
+		<-done
+		errs = append(errs, fmt.Errorf("%s grpc server graceful stop: %w", s.opts.Name, ctx.Err()))
+	}
+	s.closeGateway()
+	s.closeAdmin()
+	return errors.Join(errs...)
+}
+
 // NewGrpcServer 创建Grpc服务端
 func NewGrpcServer(ctx context.Context, opts ...ServerOption) *GrpcServer {
 	server := new(GrpcServer)
 	server.ctx = ctx
+	server.ready = make(chan struct{})
+	server.done = make(chan struct{})
 	server.opts = NewServerOptions(opts...)
-	var grpcServerOptions []grpc.ServerOption
-	if len(server.opts.StreamServerInterceptors) > 0 {
-		grpcServerOptions = append(grpcServerOptions, grpc.ChainStreamInterceptor(server.opts.StreamServerInterceptors...))
+	if err := server.opts.validateTransport(); err != nil {
+		server.optionsErr = fmt.Errorf("%s grpc server transport options: %w", server.opts.Name, err)
+	}
+	grpcServerOptions := server.opts.transportServerOptions()
+	if creds := server.opts.serverCredentials(); creds != nil {
+		grpcServerOptions = append(grpcServerOptions, grpc.Creds(creds))
 	}
-	if len(server.opts.UnaryServerInterceptors) > 0 {
-		grpcServerOptions = append(grpcServerOptions, grpc.ChainUnaryInterceptor(server.opts.UnaryServerInterceptors...))
+	var streamServerInterceptors []grpc.StreamServerInterceptor
+	var unaryServerInterceptors []grpc.UnaryServerInterceptor
+	if server.opts.GatewayAddress != "" {
+		streamServerInterceptors = append(streamServerInterceptors, gatewayPeerStreamServerInterceptor())
+		unaryServerInterceptors = append(unaryServerInterceptors, gatewayPeerUnaryServerInterceptor())
+	}
+	if handler := server.opts.serverStatsHandler(); handler != nil {
+		grpcServerOptions = append(grpcServerOptions, grpc.StatsHandler(handler))
+		if server.opts.TracerProvider != nil {
+			streamServerInterceptors = append(streamServerInterceptors, server.tracingStreamServerInterceptor())
+			unaryServerInterceptors = append(unaryServerInterceptors, server.tracingUnaryServerInterceptor())
+		}
+	}
+	server.SetMethodAllowList(server.opts.AllowedMethods)
+	streamServerInterceptors = append(streamServerInterceptors, server.allowListStreamServerInterceptor())
+	unaryServerInterceptors = append(unaryServerInterceptors, server.allowListUnaryServerInterceptor())
+	streamServerInterceptors = append(streamServerInterceptors, server.opts.streamInterceptors...)
+	streamServerInterceptors = append(streamServerInterceptors, server.opts.StreamServerInterceptors...)
+	unaryServerInterceptors = append(unaryServerInterceptors, server.opts.unaryInterceptors...)
+	unaryServerInterceptors = append(unaryServerInterceptors, server.opts.UnaryServerInterceptors...)
+	if len(streamServerInterceptors) > 0 {
+		grpcServerOptions = append(grpcServerOptions, grpc.ChainStreamInterceptor(streamServerInterceptors...))
+	}
+	if len(unaryServerInterceptors) > 0 {
+		grpcServerOptions = append(grpcServerOptions, grpc.ChainUnaryInterceptor(unaryServerInterceptors...))
 	}
 	server.server = grpc.NewServer(grpcServerOptions...)
+	server.health = newHealthServer()
+	for _, hook := range server.opts.hooks {
+		hook(server)
+	}
 	return server
 }
@@ -0,0 +1,113 @@
+package ngrpc
+
+import (
+	"context"
+
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/stats"
+)
+
+const (
+	// AttributeServiceName 服务名称
+	AttributeServiceName = attribute.Key("ngrpc.service.name")
+	// AttributeRegistryDomain 注册中心域
+	AttributeRegistryDomain = attribute.Key("ngrpc.registry.domain")
+	// AttributeInstanceAddress 实例地址
+	AttributeInstanceAddress = attribute.Key("ngrpc.instance.address")
+)
+
+// tracePropagator W3C trace context 和 baggage 通过 metadata 传播
+var tracePropagator = propagation.NewCompositeTextMapPropagator(
+	propagation.TraceContext{},
+	propagation.Baggage{},
+)
+
+// WithServerTracing 启用服务端链路追踪
+func WithServerTracing(tracerProvider trace.TracerProvider) ServerOption {
+	return func(o *ServerOptions) {
+		o.TracerProvider = tracerProvider
+	}
+}
+
+// WithServerMetrics 启用服务端指标
+func WithServerMetrics(meterProvider metric.MeterProvider) ServerOption {
+	return func(o *ServerOptions) {
+		o.MeterProvider = meterProvider
+	}
+}
+
+// WithClientTracing 启用客户端链路追踪
+func WithClientTracing(tracerProvider trace.TracerProvider) ClientOption {
+	return func(o *ClientOptions) {
+		o.TracerProvider = tracerProvider
+	}
+}
+
+// WithClientMetrics 启用客户端指标
+func WithClientMetrics(meterProvider metric.MeterProvider) ClientOption {
+	return func(o *ClientOptions) {
+		o.MeterProvider = meterProvider
+	}
+}
+
+func otelOptions(tracerProvider trace.TracerProvider, meterProvider metric.MeterProvider) (opts []otelgrpc.Option) {
+	opts = append(opts, otelgrpc.WithPropagators(tracePropagator))
+	if tracerProvider != nil {
+		opts = append(opts, otelgrpc.WithTracerProvider(tracerProvider))
+	}
+	if meterProvider != nil {
+		opts = append(opts, otelgrpc.WithMeterProvider(meterProvider))
+	}
+	return
+}
+
+// serverStatsHandler 未配置追踪和指标时返回nil
+func (o *ServerOptions) serverStatsHandler() stats.Handler {
+	if o.TracerProvider == nil && o.MeterProvider == nil {
+		return nil
+	}
+	return otelgrpc.NewServerHandler(otelOptions(o.TracerProvider, o.MeterProvider)...)
+}
+
+// clientStatsHandler 未配置追踪和指标时返回nil
+func (o *ClientOptions) clientStatsHandler() stats.Handler {
+	if o.TracerProvider == nil && o.MeterProvider == nil {
+		return nil
+	}
+	opts := otelOptions(o.TracerProvider, o.MeterProvider)
+	opts = append(opts, otelgrpc.WithSpanAttributes(AttributeServiceName.String(o.Name)))
+	return otelgrpc.NewClientHandler(opts...)
+}
+
+// spanAttributes 服务实例的span属性
+func (s *GrpcServer) spanAttributes() []attribute.KeyValue {
+	attrs := []attribute.KeyValue{
+		AttributeServiceName.String(s.opts.Name),
+	}
+	if d, ok := s.opts.register.(interface{ Domain() string }); ok {
+		attrs = append(attrs, AttributeRegistryDomain.String(d.Domain()))
+	}
+	if s.serviceInfo != nil {
+		attrs = append(attrs, AttributeInstanceAddress.String(s.serviceInfo.Address))
+	}
+	return attrs
+}
+
+func (s *GrpcServer) tracingUnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+		trace.SpanFromContext(ctx).SetAttributes(s.spanAttributes()...)
+		return handler(ctx, req)
+	}
+}
+
+func (s *GrpcServer) tracingStreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		trace.SpanFromContext(stream.Context()).SetAttributes(s.spanAttributes()...)
+		return handler(srv, stream)
+	}
+}
@@ -0,0 +1,105 @@
+package ngrpc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestTracingPropagation(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	t.Cleanup(func() { tp.Shutdown(context.Background()) })
+
+	server := startEchoServer(t,
+		WithServerTracing(tp),
+		WithServerName("echo"),
+		WithServerRegister(&domainRegistry{domain: "prod"}),
+	)
+	client := NewGrpcClient(context.Background(),
+		WithClientAddress(server.Addr().String()),
+		WithClientName("echo-client"),
+		WithClientTracing(tp),
+		WithClientLogger(nopLogger{}),
+	)
+	t.Cleanup(func() { client.close() })
+
+	ctx, parent := tp.Tracer("test").Start(context.Background(), "parent")
+	reply := new(wrapperspb.StringValue)
+	if err := client.GetConn().Invoke(ctx, echoUnary, wrapperspb.String("traced"), reply); err != nil {
+		t.Fatal(err)
+	}
+	parent.End()
+
+	// 服务端 span 可能在客户端收到响应后才结束
+	var spans tracetest.SpanStubs
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if spans = exporter.GetSpans(); len(spans) >= 3 {
+			break
+		}
+	}
+	var clientSpan, serverSpan *tracetest.SpanStub
+	for i := range spans {
+		switch spans[i].SpanKind {
+		case trace.SpanKindClient:
+			clientSpan = &spans[i]
+		case trace.SpanKindServer:
+			serverSpan = &spans[i]
+		}
+	}
+	if clientSpan == nil || serverSpan == nil {
+		t.Fatalf("got %d spans, want client and server spans", len(spans))
+	}
+	const name = "ngrpc.test.Echo/Unary"
+	if clientSpan.Name != name || serverSpan.Name != name {
+		t.Fatalf("span names %q and %q, want %q", clientSpan.Name, serverSpan.Name, name)
+	}
+	traceID := parent.SpanContext().TraceID()
+	if clientSpan.SpanContext.TraceID() != traceID || serverSpan.SpanContext.TraceID() != traceID {
+		t.Fatalf("trace ids %s and %s, want %s", clientSpan.SpanContext.TraceID(), serverSpan.SpanContext.TraceID(), traceID)
+	}
+	if clientSpan.Parent.SpanID() != parent.SpanContext().SpanID() {
+		t.Fatalf("client span parent %s, want %s", clientSpan.Parent.SpanID(), parent.SpanContext().SpanID())
+	}
+	if serverSpan.Parent.SpanID() != clientSpan.SpanContext.SpanID() || !serverSpan.Parent.IsRemote() {
+		t.Fatalf("server span parent %s, want remote %s", serverSpan.Parent.SpanID(), clientSpan.SpanContext.SpanID())
+	}
+
+	want := map[attribute.Key]string{
+		AttributeServiceName:     "echo",
+		AttributeRegistryDomain:  "prod",
+		AttributeInstanceAddress: server.Addr().String(),
+	}
+	got := spanAttributes(serverSpan)
+	for key, value := range want {
+		if got[key] != value {
+			t.Fatalf("server span %s = %q, want %q", key, got[key], value)
+		}
+	}
+	if got := spanAttributes(clientSpan); got[AttributeServiceName] != "echo-client" {
+		t.Fatalf("client span %s = %q, want %q", AttributeServiceName, got[AttributeServiceName], "echo-client")
+	}
+}
+
+// spanAttributes span 的字符串属性
+func spanAttributes(span *tracetest.SpanStub) map[attribute.Key]string {
+	attrs := make(map[attribute.Key]string, len(span.Attributes))
+	for _, kv := range span.Attributes {
+		attrs[kv.Key] = kv.Value.Emit()
+	}
+	return attrs
+}
+
+// domainRegistry 带域名的注册中心
+type domainRegistry struct {
+	recordRegistry
+	domain string
+}
+
+func (r *domainRegistry) Domain() string { return r.domain }
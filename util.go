@@ -3,6 +3,7 @@ package ngrpc
 import (
 	"errors"
 	"net"
+	"strings"
 )
 
 // LocalIPv4 本地IP
@@ -32,3 +33,38 @@ func LocalIPv4(name ...string) (ipAddr string, err error) {
 	err = errors.New("not found local ip")
 	return
 }
+
+// MatchMethod 判断grpc完整方法名是否匹配，pattern 支持 * 结尾的前缀匹配，如 /pkg.Service/*
+func MatchMethod(pattern, fullMethod string) bool {
+	if pattern == "*" {
+		return true
+	}
+	if strings.HasSuffix(pattern, "*") {
+		return strings.HasPrefix(fullMethod, strings.TrimSuffix(pattern, "*"))
+	}
+	return pattern == fullMethod
+}
+
+// matchAnyMethod 判断方法是否匹配任一规则
+func matchAnyMethod(patterns []string, fullMethod string) bool {
+	for _, pattern := range patterns {
+		if MatchMethod(pattern, fullMethod) {
+			return true
+		}
+	}
+	return false
+}
+
+// lookupMethod 查找方法对应的值，优先完全匹配，其次匹配最长的 * 规则
+func lookupMethod[T any](values map[string]T, fullMethod string) (value T, ok bool) {
+	if value, ok = values[fullMethod]; ok {
+		return
+	}
+	var longest int
+	for pattern, v := range values {
+		if len(pattern) > longest && MatchMethod(pattern, fullMethod) {
+			value, ok, longest = v, true, len(pattern)
+		}
+	}
+	return
+}
@@ -0,0 +1,884 @@
+package ngrpc
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	spb "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+const (
+	// webFrameTrailer gRPC-Web 的 trailer 帧标志
+	webFrameTrailer = 0x80
+	// connectFrameEndStream Connect 流式响应的结束帧标志
+	connectFrameEndStream = 0x02
+	// connectProtocolVersion Connect 协议版本请求头
+	connectProtocolVersion = "Connect-Protocol-Version"
+)
+
+// webProtocol 浏览器协议类型
+type webProtocol int
+
+const (
+	protocolGrpcWeb webProtocol = iota + 1
+	protocolConnectUnary
+	protocolConnectStream
+)
+
+// WebOptions gRPC-Web 和 Connect 协议配置
+type WebOptions struct {
+	// AllowedOrigins 允许跨域访问的 Origin，* 表示全部，为空时不返回 CORS 头；
+	// AllowCredentials 为 true 时必须列出具体的 Origin，包含 * 时 Run 返回错误
+	AllowedOrigins []string
+	// AllowedHeaders 在协议所需请求头之外允许的请求头，如 Authorization
+	AllowedHeaders []string
+	// ExposedHeaders 在协议所需响应头之外暴露给浏览器的响应头
+	ExposedHeaders []string
+	// AllowCredentials 是否允许携带 Cookie 等凭证
+	AllowCredentials bool
+	// MaxAge 预检请求缓存时间
+	MaxAge time.Duration
+}
+
+// WithServerWeb 在 gRPC 端口上接受 gRPC-Web（binary 和 text）和 Connect 协议请求，
+// 支持 HTTP/1.1 和 HTTP/2，请求转换后交给 GetSrv() 上注册的服务处理；
+// 该模式下由 net/http 处理连接，TLS 使用 WithServerHTTPTLSConfig，限制同 WithServerHTTPHandler
+func WithServerWeb(opts WebOptions) ServerOption {
+	return func(o *ServerOptions) {
+		o.Web = &opts
+	}
+}
+
+// defaultMaxRecvMsgSize 未设置 MaxRecvMsgSize 时的请求消息大小上限，与 grpc 默认值一致
+const defaultMaxRecvMsgSize = 4 << 20
+
+// maxRecvMsgSize 请求消息大小上限
+func (o *ServerOptions) maxRecvMsgSize() int {
+	if o.MaxRecvMsgSize > 0 {
+		return o.MaxRecvMsgSize
+	}
+	return defaultMaxRecvMsgSize
+}
+
+// methodDescriptor 按 /service/method 查找方法描述符，未注册描述符时返回nil
+func methodDescriptor(path string) protoreflect.MethodDescriptor {
+	service, method, ok := strings.Cut(strings.TrimPrefix(path, "/"), "/")
+	if !ok {
+		return nil
+	}
+	d, err := protoregistry.GlobalFiles.FindDescriptorByName(protoreflect.FullName(service + "." + method))
+	if err != nil {
+		return nil
+	}
+	md, _ := d.(protoreflect.MethodDescriptor)
+	return md
+}
+
+// sideEffectFree 方法是否声明了 idempotency_level = NO_SIDE_EFFECTS
+func sideEffectFree(md protoreflect.MethodDescriptor) bool {
+	if md == nil {
+		return false
+	}
+	options, ok := md.Options().(*descriptorpb.MethodOptions)
+	return ok && options.GetIdempotencyLevel() == descriptorpb.MethodOptions_NO_SIDE_EFFECTS
+}
+
+// webTranscoder 在 JSON 和 proto 编码之间转换消息，grpc.Server 只处理 proto 编码
+type webTranscoder struct {
+	input, output protoreflect.MessageType
+}
+
+func newWebTranscoder(md protoreflect.MethodDescriptor) *webTranscoder {
+	return &webTranscoder{input: webMessageType(md.Input()), output: webMessageType(md.Output())}
+}
+
+// webMessageType 优先使用生成代码注册的类型
+func webMessageType(md protoreflect.MessageDescriptor) protoreflect.MessageType {
+	if mt, err := protoregistry.GlobalTypes.FindMessageByName(md.FullName()); err == nil {
+		return mt
+	}
+	return dynamicpb.NewMessageType(md)
+}
+
+func (t *webTranscoder) toProto(data []byte) ([]byte, error) {
+	msg := t.input.New().Interface()
+	if err := (protojson.UnmarshalOptions{DiscardUnknown: true}).Unmarshal(data, msg); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "json: %v", err)
+	}
+	return proto.Marshal(msg)
+}
+
+func (t *webTranscoder) toJSON(data []byte) ([]byte, error) {
+	msg := t.output.New().Interface()
+	if err := proto.Unmarshal(data, msg); err != nil {
+		return nil, err
+	}
+	return protojson.Marshal(msg)
+}
+
+// connectCodes Connect 错误码名称和对应的 HTTP 状态码
+var connectCodes = map[codes.Code]struct {
+	name   string
+	status int
+}{
+	codes.Canceled:           {"canceled", 499},
+	codes.Unknown:            {"unknown", http.StatusInternalServerError},
+	codes.InvalidArgument:    {"invalid_argument", http.StatusBadRequest},
+	codes.DeadlineExceeded:   {"deadline_exceeded", http.StatusGatewayTimeout},
+	codes.NotFound:           {"not_found", http.StatusNotFound},
+	codes.AlreadyExists:      {"already_exists", http.StatusConflict},
+	codes.PermissionDenied:   {"permission_denied", http.StatusForbidden},
+	codes.ResourceExhausted:  {"resource_exhausted", http.StatusTooManyRequests},
+	codes.FailedPrecondition: {"failed_precondition", http.StatusBadRequest},
+	codes.Aborted:            {"aborted", http.StatusConflict},
+	codes.OutOfRange:         {"out_of_range", http.StatusBadRequest},
+	codes.Unimplemented:      {"unimplemented", http.StatusNotImplemented},
+	codes.Internal:           {"internal", http.StatusInternalServerError},
+	codes.Unavailable:        {"unavailable", http.StatusServiceUnavailable},
+	codes.DataLoss:           {"data_loss", http.StatusInternalServerError},
+	codes.Unauthenticated:    {"unauthenticated", http.StatusUnauthorized},
+}
+
+// webProtocolOf 判断 gRPC-Web 或 Connect 请求，Connect 请求须指向已注册的方法
+func (s *GrpcServer) webProtocolOf(r *http.Request) webProtocol {
+	contentType := r.Header.Get("Content-Type")
+	switch {
+	case r.Method == http.MethodPost && strings.HasPrefix(contentType, "application/grpc-web"):
+		return protocolGrpcWeb
+	case !s.isRegisteredMethod(r.URL.Path):
+		return 0
+	case r.Method == http.MethodGet && r.URL.Query().Has("message"):
+		return protocolConnectUnary
+	case r.Method != http.MethodPost:
+		return 0
+	case strings.HasPrefix(contentType, "application/connect+"):
+		return protocolConnectStream
+	case strings.HasPrefix(contentType, "application/proto"), strings.HasPrefix(contentType, "application/json"):
+		return protocolConnectUnary
+	}
+	return 0
+}
+
+// isRegisteredMethod 路径是否为已注册的 /service/method
+func (s *GrpcServer) isRegisteredMethod(path string) bool {
+	service, method, ok := strings.Cut(strings.TrimPrefix(path, "/"), "/")
+	if !ok {
+		return false
+	}
+	info, ok := s.server.GetServiceInfo()[service]
+	if !ok {
+		return false
+	}
+	for _, m := range info.Methods {
+		if m.Name == method {
+			return true
+		}
+	}
+	return false
+}
+
+// isPreflight 判断是否为发往已注册方法的 CORS 预检请求
+func (s *GrpcServer) isPreflight(r *http.Request) bool {
+	return r.Method == http.MethodOptions && r.Header.Get("Origin") != "" &&
+		r.Header.Get("Access-Control-Request-Method") != "" && s.isRegisteredMethod(r.URL.Path)
+}
+
+// validate 校验 CORS 配置，允许携带凭证时不能允许全部 Origin，否则任意网站都能以用户身份调用
+func (o *WebOptions) validate() error {
+	if o.AllowCredentials && slices.Contains(o.AllowedOrigins, "*") {
+		return errors.New("web: allowed origins must be explicit when credentials are allowed")
+	}
+	return nil
+}
+
+// allowedOrigin 返回允许的 Origin，不允许时返回空
+func (o *WebOptions) allowedOrigin(origin string) string {
+	for _, allowed := range o.AllowedOrigins {
+		if allowed == "*" {
+			return "*"
+		}
+		if strings.EqualFold(allowed, origin) {
+			return origin
+		}
+	}
+	return ""
+}
+
+// setCORSHeaders 设置跨域响应头，返回是否允许该 Origin
+func (o *WebOptions) setCORSHeaders(w http.ResponseWriter, r *http.Request, preflight bool) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return false
+	}
+	h := w.Header()
+	h.Add("Vary", "Origin")
+	allowed := o.allowedOrigin(origin)
+	if allowed == "" {
+		return false
+	}
+	h.Set("Access-Control-Allow-Origin", allowed)
+	if o.AllowCredentials {
+		h.Set("Access-Control-Allow-Credentials", "true")
+	}
+	if !preflight {
+		exposed := []string{"Grpc-Status", "Grpc-Message", "Grpc-Status-Details-Bin", "Connect-Content-Encoding", "Content-Encoding"}
+		h.Set("Access-Control-Expose-Headers", strings.Join(append(exposed, o.ExposedHeaders...), ", "))
+		return true
+	}
+	h.Add("Vary", "Access-Control-Request-Method")
+	h.Add("Vary", "Access-Control-Request-Headers")
+	h.Set("Access-Control-Allow-Methods", "GET, POST")
+	headers := []string{"Content-Type", "Content-Encoding", "Accept-Encoding", "Grpc-Timeout", "X-Grpc-Web", "X-User-Agent",
+		connectProtocolVersion, "Connect-Timeout-Ms", "Connect-Content-Encoding", "Connect-Accept-Encoding"}
+	h.Set("Access-Control-Allow-Headers", strings.Join(append(headers, o.AllowedHeaders...), ", "))
+	if o.MaxAge > 0 {
+		h.Set("Access-Control-Max-Age", strconv.Itoa(int(o.MaxAge.Seconds())))
+	}
+	return true
+}
+
+// servePreflight 响应 CORS 预检请求
+func (s *GrpcServer) servePreflight(w http.ResponseWriter, r *http.Request) {
+	if !s.opts.Web.setCORSHeaders(w, r, true) {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// serveWeb 将 gRPC-Web 或 Connect 请求转换为 gRPC 请求交给 grpc.Server 处理
+func (s *GrpcServer) serveWeb(w http.ResponseWriter, r *http.Request, protocol webProtocol) {
+	s.opts.Web.setCORSHeaders(w, r, false)
+	md := methodDescriptor(r.URL.Path)
+	if r.Method == http.MethodGet && !sideEffectFree(md) {
+		// GET 可被跨站请求触发，只允许无副作用的方法
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "GET is only allowed for methods without side effects", http.StatusMethodNotAllowed)
+		return
+	}
+	// HTTP/1.1 下允许在读取请求体的同时写响应
+	_ = http.NewResponseController(w).EnableFullDuplex()
+	ww := &webResponseWriter{w: w, header: make(http.Header), protocol: protocol, limit: s.opts.maxRecvMsgSize()}
+	req, err := ww.grpcRequest(r, md)
+	if err != nil {
+		if st, ok := status.FromError(err); ok {
+			ww.writeError(st.Code(), st.Message())
+		} else {
+			ww.writeError(codes.InvalidArgument, err.Error())
+		}
+		return
+	}
+	s.server.ServeHTTP(ww, req)
+	ww.finish()
+}
+
+// grpcRequest 转换请求头和请求体，JSON 编码的消息转换为 proto 编码
+func (ww *webResponseWriter) grpcRequest(r *http.Request, md protoreflect.MethodDescriptor) (*http.Request, error) {
+	req := r.Clone(r.Context())
+	req.ProtoMajor, req.ProtoMinor, req.Proto = 2, 0, "HTTP/2.0"
+	req.ContentLength = -1
+	h := req.Header
+	h.Del("Content-Length")
+	h.Set("Te", "trailers")
+	contentType := h.Get("Content-Type")
+	switch ww.protocol {
+	case protocolGrpcWeb:
+		ww.contentType = contentType
+		subtype := strings.TrimPrefix(strings.TrimPrefix(contentType, "application/grpc-web"), "-text")
+		h.Set("Content-Type", "application/grpc"+subtype)
+		var body io.Reader = r.Body
+		if strings.HasPrefix(contentType, "application/grpc-web-text") {
+			ww.text = true
+			body = &base64Reader{r: bufio.NewReader(r.Body)}
+		}
+		if isJSONSubtype(strings.TrimPrefix(subtype, "+")) {
+			var err error
+			if body, err = ww.transcodeStream(md, h, body); err != nil {
+				return nil, err
+			}
+		}
+		req.Body = io.NopCloser(body)
+		return req, nil
+	case protocolConnectStream:
+		ww.contentType = contentType
+		subtype := strings.TrimPrefix(contentType, "application/connect+")
+		h.Set("Content-Type", "application/grpc+"+subtype)
+		renameHeader(h, "Connect-Content-Encoding", "Grpc-Encoding")
+		renameHeader(h, "Connect-Accept-Encoding", "Grpc-Accept-Encoding")
+		if isJSONSubtype(subtype) {
+			body, err := ww.transcodeStream(md, h, r.Body)
+			if err != nil {
+				return nil, err
+			}
+			req.Body = io.NopCloser(body)
+		}
+	case protocolConnectUnary:
+		codec, body, err := connectUnaryBody(r, ww.limit)
+		if err != nil {
+			return nil, err
+		}
+		ww.contentType = "application/" + codec
+		req.Method = http.MethodPost
+		h.Set("Content-Type", "application/grpc+"+codec)
+		if codec == "json" {
+			if ww.transcoder, err = newJSONTranscoder(md); err != nil {
+				return nil, err
+			}
+			if body, err = ww.transcoder.toProto(body); err != nil {
+				return nil, err
+			}
+			h.Set("Content-Type", "application/grpc")
+		}
+		h.Del("Content-Encoding")
+		h.Del("Accept-Encoding")
+		req.Body = io.NopCloser(bytes.NewReader(grpcFrame(body)))
+	}
+	h.Del(connectProtocolVersion)
+	if timeout := h.Get("Connect-Timeout-Ms"); timeout != "" {
+		h.Del("Connect-Timeout-Ms")
+		ms, err := strconv.ParseUint(timeout, 10, 63)
+		if err != nil {
+			return nil, fmt.Errorf("invalid Connect-Timeout-Ms %q", timeout)
+		}
+		h.Set("Grpc-Timeout", grpcTimeout(ms))
+	}
+	return req, nil
+}
+
+// grpcTimeout 毫秒数对应的 grpc-timeout，数值最多8位，超出时向上取整到更大的单位
+func grpcTimeout(ms uint64) string {
+	const maxValue = 99999999
+	value, unit := ms, "m"
+	for _, next := range []struct {
+		scale uint64
+		unit  string
+	}{{1000, "S"}, {60, "M"}, {60, "H"}} {
+		if value <= maxValue {
+			break
+		}
+		value, unit = (value+next.scale-1)/next.scale, next.unit
+	}
+	return strconv.FormatUint(min(value, maxValue), 10) + unit
+}
+
+// isJSONSubtype content-type 子类型是否为 json，忽略参数
+func isJSONSubtype(subtype string) bool {
+	subtype, _, _ = strings.Cut(subtype, ";")
+	return strings.TrimSpace(subtype) == "json"
+}
+
+// newJSONTranscoder JSON 编码需要方法描述符
+func newJSONTranscoder(md protoreflect.MethodDescriptor) (*webTranscoder, error) {
+	if md == nil {
+		return nil, status.Error(codes.Unimplemented, "json encoding requires a registered method descriptor")
+	}
+	return newWebTranscoder(md), nil
+}
+
+// transcodeStream 将 JSON 编码的请求流转换为 proto 编码，压缩的消息在转换前解压
+func (ww *webResponseWriter) transcodeStream(md protoreflect.MethodDescriptor, h http.Header, body io.Reader) (io.Reader, error) {
+	transcoder, err := newJSONTranscoder(md)
+	if err != nil {
+		return nil, err
+	}
+	ww.transcoder = transcoder
+	h.Set("Content-Type", "application/grpc")
+	compression := h.Get("Grpc-Encoding")
+	// 转换后的请求和响应都不压缩
+	h.Del("Grpc-Encoding")
+	h.Del("Grpc-Accept-Encoding")
+	return &jsonFrameReader{r: body, ww: ww, compression: compression}, nil
+}
+
+// grpcFrame 添加未压缩消息的长度前缀
+func grpcFrame(message []byte) []byte {
+	frame := make([]byte, 5, 5+len(message))
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(message)))
+	return append(frame, message...)
+}
+
+// readLimited 读取全部内容，超过 limit 时返回 ResourceExhausted
+func readLimited(r io.Reader, limit int) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, int64(limit)+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > limit {
+		return nil, status.Errorf(codes.ResourceExhausted, "message larger than max %d bytes", limit)
+	}
+	return data, nil
+}
+
+// decompress 解压消息，解压后的大小不超过 limit
+func decompress(compression string, data []byte, limit int) ([]byte, error) {
+	switch compression {
+	case "", "identity":
+		return data, nil
+	case "gzip":
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		return readLimited(zr, limit)
+	}
+	return nil, status.Errorf(codes.Unimplemented, "unsupported compression %q", compression)
+}
+
+// connectUnaryBody 读取 Connect 一元请求的编码和消息，支持 POST 和 GET，消息大小不超过 limit
+func connectUnaryBody(r *http.Request, limit int) (codec string, body []byte, err error) {
+	compression := r.Header.Get("Content-Encoding")
+	if r.Method == http.MethodGet {
+		query := r.URL.Query()
+		codec = query.Get("encoding")
+		compression = query.Get("compression")
+		body = []byte(query.Get("message"))
+		if query.Get("base64") == "1" {
+			if body, err = base64.RawURLEncoding.DecodeString(strings.TrimRight(query.Get("message"), "=")); err != nil {
+				return "", nil, fmt.Errorf("invalid message: %w", err)
+			}
+		}
+	} else {
+		codec = strings.TrimPrefix(r.Header.Get("Content-Type"), "application/")
+		if body, err = readLimited(r.Body, limit); err != nil {
+			return "", nil, err
+		}
+	}
+	if codec, _, _ = strings.Cut(codec, ";"); codec != "proto" && codec != "json" {
+		return "", nil, fmt.Errorf("unsupported encoding %q", codec)
+	}
+	if body, err = decompress(compression, body, limit); err != nil {
+		return "", nil, err
+	}
+	return codec, body, nil
+}
+
+// jsonFrameReader 逐条读取 JSON 编码的请求消息并转换为未压缩的 proto 消息
+type jsonFrameReader struct {
+	r           io.Reader
+	ww          *webResponseWriter
+	compression string
+	buf         []byte
+	err         error
+}
+
+func (f *jsonFrameReader) Read(p []byte) (int, error) {
+	for len(f.buf) == 0 {
+		if f.err != nil {
+			return 0, f.err
+		}
+		frame, err := f.next()
+		if err != nil {
+			f.err = err
+			if st, ok := status.FromError(err); ok {
+				// grpc.Server 不会原样返回读取请求体的错误，在 trailer 中替换
+				f.ww.requestErr.Store(st)
+			}
+			continue
+		}
+		f.buf = frame
+	}
+	n := copy(p, f.buf)
+	f.buf = f.buf[n:]
+	return n, nil
+}
+
+func (f *jsonFrameReader) next() ([]byte, error) {
+	var prefix [5]byte
+	if _, err := io.ReadFull(f.r, prefix[:]); err != nil {
+		if err == io.EOF {
+			return nil, io.EOF
+		}
+		return nil, status.Errorf(codes.InvalidArgument, "read message: %v", err)
+	}
+	n := binary.BigEndian.Uint32(prefix[1:])
+	if uint64(n) > uint64(f.ww.limit) {
+		return nil, status.Errorf(codes.ResourceExhausted, "message larger than max %d bytes", f.ww.limit)
+	}
+	message := make([]byte, n)
+	if _, err := io.ReadFull(f.r, message); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "read message: %v", err)
+	}
+	var err error
+	if prefix[0]&1 == 1 {
+		if f.compression == "" || f.compression == "identity" {
+			return nil, status.Error(codes.Internal, "compressed message without encoding")
+		}
+		if message, err = decompress(f.compression, message, f.ww.limit); err != nil {
+			return nil, err
+		}
+	}
+	if message, err = f.ww.transcoder.toProto(message); err != nil {
+		return nil, err
+	}
+	return grpcFrame(message), nil
+}
+
+func renameHeader(h http.Header, from, to string) {
+	if v, ok := h[textproto.CanonicalMIMEHeaderKey(from)]; ok {
+		h.Del(from)
+		h[textproto.CanonicalMIMEHeaderKey(to)] = v
+	}
+}
+
+// webResponseWriter 将 grpc.Server 的响应转换为 gRPC-Web 或 Connect 响应
+type webResponseWriter struct {
+	w        http.ResponseWriter
+	header   http.Header
+	protocol webProtocol
+	// contentType 响应的 content-type
+	contentType string
+	// text gRPC-Web text 模式，响应体使用 base64 编码
+	text bool
+	// pending text 模式下未编码的字节，按3字节对齐编码
+	pending []byte
+	// body Connect 一元调用缓存的响应体
+	body bytes.Buffer
+	// limit 请求消息大小上限
+	limit int
+	// transcoder JSON 编码时转换消息，raw 为未转换完的响应
+	transcoder *webTranscoder
+	raw        []byte
+	// requestErr 读取请求消息的错误，替换 grpc.Server 返回的状态
+	requestErr  atomic.Pointer[status.Status]
+	wroteHeader bool
+}
+
+func (ww *webResponseWriter) Header() http.Header {
+	return ww.header
+}
+
+func (ww *webResponseWriter) WriteHeader(int) {
+	if ww.wroteHeader {
+		return
+	}
+	ww.wroteHeader = true
+	if ww.protocol == protocolConnectUnary {
+		return
+	}
+	h := ww.w.Header()
+	copyResponseHeaders(h, ww.header)
+	h.Set("Content-Type", ww.contentType)
+	if ww.protocol == protocolConnectStream {
+		renameHeader(h, "Grpc-Encoding", "Connect-Content-Encoding")
+	}
+	ww.w.WriteHeader(http.StatusOK)
+}
+
+func (ww *webResponseWriter) Write(p []byte) (int, error) {
+	ww.WriteHeader(http.StatusOK)
+	if ww.transcoder == nil {
+		return ww.write(p)
+	}
+	ww.raw = append(ww.raw, p...)
+	for len(ww.raw) >= 5 {
+		n := int(binary.BigEndian.Uint32(ww.raw[1:5]))
+		if len(ww.raw) < 5+n {
+			break
+		}
+		if ww.raw[0]&1 == 1 {
+			return 0, errors.New("json: unexpected compressed response message")
+		}
+		message, err := ww.transcoder.toJSON(ww.raw[5 : 5+n])
+		if err != nil {
+			return 0, err
+		}
+		ww.raw = ww.raw[5+n:]
+		if _, err = ww.write(grpcFrame(message)); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// write 按协议写出已转换的响应
+func (ww *webResponseWriter) write(p []byte) (int, error) {
+	if ww.protocol == protocolConnectUnary {
+		return ww.body.Write(p)
+	}
+	if !ww.text {
+		return ww.w.Write(p)
+	}
+	ww.pending = append(ww.pending, p...)
+	n := len(ww.pending) / 3 * 3
+	if n > 0 {
+		if _, err := ww.w.Write([]byte(base64.StdEncoding.EncodeToString(ww.pending[:n]))); err != nil {
+			return 0, err
+		}
+		ww.pending = append(ww.pending[:0], ww.pending[n:]...)
+	}
+	return len(p), nil
+}
+
+func (ww *webResponseWriter) Flush() {
+	ww.WriteHeader(http.StatusOK)
+	if ww.protocol == protocolConnectUnary {
+		return
+	}
+	if len(ww.pending) > 0 {
+		ww.w.Write([]byte(base64.StdEncoding.EncodeToString(ww.pending)))
+		ww.pending = ww.pending[:0]
+	}
+	if f, ok := ww.w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// copyResponseHeaders 复制响应头，跳过 trailer 相关的头
+func copyResponseHeaders(dst, src http.Header) {
+	for k, v := range src {
+		switch {
+		case k == "Trailer", k == "Grpc-Status", k == "Grpc-Message", k == "Grpc-Status-Details-Bin",
+			strings.HasPrefix(k, http.TrailerPrefix):
+			continue
+		}
+		dst[k] = v
+	}
+}
+
+// trailers grpc.Server 写入的 trailer，key 为小写
+func (ww *webResponseWriter) trailers() map[string][]string {
+	trailers := make(map[string][]string)
+	for k, v := range ww.header {
+		switch {
+		case k == "Grpc-Status", k == "Grpc-Message", k == "Grpc-Status-Details-Bin":
+		case strings.HasPrefix(k, http.TrailerPrefix):
+			k = strings.TrimPrefix(k, http.TrailerPrefix)
+		default:
+			continue
+		}
+		k = strings.ToLower(k)
+		trailers[k] = append(trailers[k], v...)
+	}
+	return trailers
+}
+
+// finish grpc.Server 处理完成后写出 trailer
+func (ww *webResponseWriter) finish() {
+	trailers := ww.trailers()
+	if st := ww.requestErr.Load(); st != nil {
+		trailers["grpc-status"] = []string{strconv.Itoa(int(st.Code()))}
+		trailers["grpc-message"] = []string{url.PathEscape(st.Message())}
+	}
+	if _, ok := trailers["grpc-status"]; !ok {
+		// 连接断开等情况下没有写出状态
+		trailers["grpc-status"] = []string{strconv.Itoa(int(codes.Unknown))}
+	}
+	switch ww.protocol {
+	case protocolGrpcWeb:
+		ww.writeGrpcWebTrailers(trailers)
+	case protocolConnectStream:
+		ww.writeConnectEndStream(trailers)
+	case protocolConnectUnary:
+		ww.writeConnectUnary(trailers)
+	}
+}
+
+// writeError 请求转换失败时直接返回错误
+func (ww *webResponseWriter) writeError(code codes.Code, message string) {
+	ww.header.Set("Grpc-Status", strconv.Itoa(int(code)))
+	ww.header.Set("Grpc-Message", url.PathEscape(message))
+	ww.finish()
+}
+
+func (ww *webResponseWriter) writeGrpcWebTrailers(trailers map[string][]string) {
+	var buf bytes.Buffer
+	keys := make([]string, 0, len(trailers))
+	for k := range trailers {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		for _, v := range trailers[k] {
+			fmt.Fprintf(&buf, "%s: %s\r\n", k, v)
+		}
+	}
+	frame := make([]byte, 5, 5+buf.Len())
+	frame[0] = webFrameTrailer
+	binary.BigEndian.PutUint32(frame[1:], uint32(buf.Len()))
+	ww.WriteHeader(http.StatusOK)
+	ww.write(append(frame, buf.Bytes()...))
+	ww.Flush()
+}
+
+// connectError Connect 协议的错误
+type connectError struct {
+	Code    string               `json:"code"`
+	Message string               `json:"message,omitempty"`
+	Details []connectErrorDetail `json:"details,omitempty"`
+}
+
+type connectErrorDetail struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+// newConnectError 从 trailer 中的 gRPC 状态创建 Connect 错误，成功时返回nil和状态码
+func newConnectError(trailers map[string][]string) (*connectError, int) {
+	code := codes.Unknown
+	if v, ok := trailers["grpc-status"]; ok {
+		if c, err := strconv.Atoi(v[0]); err == nil {
+			code = codes.Code(c)
+		}
+	}
+	if code == codes.OK {
+		return nil, http.StatusOK
+	}
+	mapped, ok := connectCodes[code]
+	if !ok {
+		mapped = connectCodes[codes.Unknown]
+	}
+	e := &connectError{Code: mapped.name}
+	if v, ok := trailers["grpc-message"]; ok {
+		e.Message = v[0]
+		if message, err := url.PathUnescape(v[0]); err == nil {
+			e.Message = message
+		}
+	}
+	if v, ok := trailers["grpc-status-details-bin"]; ok {
+		if data, err := decodeBinaryHeader(v[0]); err == nil {
+			st := new(spb.Status)
+			if proto.Unmarshal(data, st) == nil {
+				for _, detail := range st.Details {
+					e.Details = append(e.Details, connectErrorDetail{
+						Type:  string(detail.MessageName()),
+						Value: base64.RawStdEncoding.EncodeToString(detail.Value),
+					})
+				}
+			}
+		}
+	}
+	return e, mapped.status
+}
+
+func decodeBinaryHeader(v string) ([]byte, error) {
+	if len(v)%4 == 0 {
+		return base64.StdEncoding.DecodeString(v)
+	}
+	return base64.RawStdEncoding.DecodeString(v)
+}
+
+// connectMetadata trailer 中除 gRPC 状态之外的 metadata
+func connectMetadata(trailers map[string][]string) map[string][]string {
+	metadata := make(map[string][]string)
+	for k, v := range trailers {
+		if !strings.HasPrefix(k, "grpc-") {
+			metadata[k] = v
+		}
+	}
+	return metadata
+}
+
+func (ww *webResponseWriter) writeConnectEndStream(trailers map[string][]string) {
+	end := struct {
+		Error    *connectError       `json:"error,omitempty"`
+		Metadata map[string][]string `json:"metadata,omitempty"`
+	}{Metadata: connectMetadata(trailers)}
+	end.Error, _ = newConnectError(trailers)
+	data, _ := json.Marshal(end)
+	frame := make([]byte, 5, 5+len(data))
+	frame[0] = connectFrameEndStream
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(data)))
+	ww.WriteHeader(http.StatusOK)
+	ww.write(append(frame, data...))
+	ww.Flush()
+}
+
+func (ww *webResponseWriter) writeConnectUnary(trailers map[string][]string) {
+	h := ww.w.Header()
+	copyResponseHeaders(h, ww.header)
+	h.Del("Grpc-Encoding")
+	for k, v := range connectMetadata(trailers) {
+		h[textproto.CanonicalMIMEHeaderKey("Trailer-"+k)] = v
+	}
+	connectErr, statusCode := newConnectError(trailers)
+	var body []byte
+	if connectErr == nil {
+		message, compressed, err := unaryMessage(ww.body.Bytes())
+		if err != nil {
+			connectErr, statusCode = &connectError{Code: connectCodes[codes.Internal].name, Message: err.Error()}, http.StatusInternalServerError
+		} else {
+			body = message
+			if compressed {
+				h.Set("Content-Encoding", ww.header.Get("Grpc-Encoding"))
+			}
+			h.Set("Content-Type", ww.contentType)
+		}
+	}
+	if connectErr != nil {
+		body, _ = json.Marshal(connectErr)
+		h.Set("Content-Type", "application/json")
+	}
+	h.Set("Content-Length", strconv.Itoa(len(body)))
+	ww.w.WriteHeader(statusCode)
+	ww.w.Write(body)
+}
+
+// unaryMessage 从 gRPC 响应体中取出唯一的消息
+func unaryMessage(data []byte) (message []byte, compressed bool, err error) {
+	if len(data) < 5 {
+		return nil, false, errors.New("missing response message")
+	}
+	n := binary.BigEndian.Uint32(data[1:5])
+	if uint64(len(data)-5) != uint64(n) {
+		return nil, false, errors.New("unexpected response message length")
+	}
+	return data[5:], data[0]&1 == 1, nil
+}
+
+// base64Reader 解码 gRPC-Web text 请求体，允许各段分别带有填充
+type base64Reader struct {
+	r   *bufio.Reader
+	buf []byte
+	err error
+}
+
+func (b *base64Reader) Read(p []byte) (int, error) {
+	for len(b.buf) == 0 {
+		if b.err != nil {
+			return 0, b.err
+		}
+		var quad [4]byte
+		n, err := io.ReadFull(b.r, quad[:])
+		if err == io.EOF {
+			b.err = io.EOF
+			continue
+		}
+		if err != nil {
+			b.err = fmt.Errorf("grpc-web-text: %w", io.ErrUnexpectedEOF)
+			continue
+		}
+		decoded := make([]byte, 3)
+		if n, err = base64.StdEncoding.Decode(decoded, quad[:]); err != nil {
+			b.err = err
+			continue
+		}
+		b.buf = decoded[:n]
+	}
+	n := copy(p, b.buf)
+	b.buf = b.buf[n:]
+	return n, nil
+}
@@ -0,0 +1,421 @@
+package ngrpc
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// webTestMaxRecvMsgSize 测试服务端的请求消息大小上限
+const webTestMaxRecvMsgSize = 1024
+
+// startWebServer 通过进程内连接启动支持 gRPC-Web 和 Connect 的 Echo 服务
+func startWebServer(t *testing.T) *http.Client {
+	t.Helper()
+	lis := newPipeListener(64 << 10)
+	startEchoServer(t,
+		WithServerListener(lis),
+		WithServerWeb(WebOptions{AllowedOrigins: []string{"*"}}),
+		WithServerMaxRecvMsgSize(webTestMaxRecvMsgSize),
+	)
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		},
+	}
+	t.Cleanup(transport.CloseIdleConnections)
+	return &http.Client{Transport: transport}
+}
+
+func webPost(t *testing.T, client *http.Client, method, contentType string, header http.Header, body []byte) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodPost, "http://pipe"+method, bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", contentType)
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func readBody(t *testing.T, resp *http.Response) []byte {
+	t.Helper()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func gzipBytes(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func marshalString(t *testing.T, value string) []byte {
+	t.Helper()
+	data, err := proto.Marshal(wrapperspb.String(value))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func unmarshalString(t *testing.T, data []byte) string {
+	t.Helper()
+	reply := new(wrapperspb.StringValue)
+	if err := proto.Unmarshal(data, reply); err != nil {
+		t.Fatal(err)
+	}
+	return reply.GetValue()
+}
+
+// webFrame 长度前缀的消息帧
+type webFrame struct {
+	flag byte
+	data []byte
+}
+
+func readFrames(t *testing.T, data []byte) []webFrame {
+	t.Helper()
+	var frames []webFrame
+	for len(data) > 0 {
+		if len(data) < 5 {
+			t.Fatalf("truncated frame prefix %q", data)
+		}
+		n := int(binary.BigEndian.Uint32(data[1:5]))
+		if len(data) < 5+n {
+			t.Fatalf("truncated frame %q", data)
+		}
+		frames = append(frames, webFrame{flag: data[0], data: data[5 : 5+n]})
+		data = data[5+n:]
+	}
+	return frames
+}
+
+// connectErrorOf 解析 Connect 一元调用的错误响应
+func connectErrorOf(t *testing.T, resp *http.Response) connectError {
+	t.Helper()
+	var e connectError
+	if err := json.Unmarshal(readBody(t, resp), &e); err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+func TestWebConnectUnary(t *testing.T) {
+	client := startWebServer(t)
+
+	resp := webPost(t, client, echoUnary, "application/json", nil, []byte(`"hello"`))
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("json: status %d: %s", resp.StatusCode, readBody(t, resp))
+	}
+	if got := resp.Header.Get("Content-Type"); got != "application/json" {
+		t.Fatalf("json: content-type %q", got)
+	}
+	if got := string(readBody(t, resp)); got != `"hello"` {
+		t.Fatalf("json: body %s", got)
+	}
+
+	resp = webPost(t, client, echoUnary, "application/proto", nil, marshalString(t, "hello"))
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("proto: status %d: %s", resp.StatusCode, readBody(t, resp))
+	}
+	if got := unmarshalString(t, readBody(t, resp)); got != "hello" {
+		t.Fatalf("proto: got %q", got)
+	}
+}
+
+func TestWebConnectUnaryError(t *testing.T) {
+	client := startWebServer(t)
+	resp := webPost(t, client, echoUnary, "application/json", nil, []byte(`"error:bad input"`))
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("status %d, want %d", resp.StatusCode, http.StatusBadRequest)
+	}
+	if e := connectErrorOf(t, resp); e.Code != "invalid_argument" || e.Message != "bad input" {
+		t.Fatalf("error = %+v", e)
+	}
+}
+
+func TestWebConnectGet(t *testing.T) {
+	client := startWebServer(t)
+	query := url.Values{"encoding": {"json"}, "message": {`"cached"`}}.Encode()
+
+	resp, err := client.Get("http://pipe" + echoGet + "?" + query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status %d: %s", resp.StatusCode, readBody(t, resp))
+	}
+	if got := string(readBody(t, resp)); got != `"cached"` {
+		t.Fatalf("body %s", got)
+	}
+
+	// 没有声明 NO_SIDE_EFFECTS 的方法不接受 GET
+	resp, err = client.Get("http://pipe" + echoUnary + "?" + query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Fatalf("status %d, want %d", resp.StatusCode, http.StatusMethodNotAllowed)
+	}
+}
+
+func TestWebConnectGzip(t *testing.T) {
+	client := startWebServer(t)
+	header := http.Header{"Content-Encoding": {"gzip"}}
+
+	resp := webPost(t, client, echoUnary, "application/json", header, gzipBytes(t, []byte(`"zipped"`)))
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status %d: %s", resp.StatusCode, readBody(t, resp))
+	}
+	if got := string(readBody(t, resp)); got != `"zipped"` {
+		t.Fatalf("body %s", got)
+	}
+
+	// 压缩后很小但解压后超过上限
+	large := []byte(`"` + strings.Repeat("a", 4*webTestMaxRecvMsgSize) + `"`)
+	resp = webPost(t, client, echoUnary, "application/json", header, gzipBytes(t, large))
+	if resp.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("status %d, want %d", resp.StatusCode, http.StatusTooManyRequests)
+	}
+	if e := connectErrorOf(t, resp); e.Code != "resource_exhausted" {
+		t.Fatalf("error = %+v", e)
+	}
+
+	resp = webPost(t, client, echoUnary, "application/json", nil, large)
+	if e := connectErrorOf(t, resp); e.Code != "resource_exhausted" {
+		t.Fatalf("uncompressed: error = %+v", e)
+	}
+}
+
+func TestWebGrpcWebServerStream(t *testing.T) {
+	client := startWebServer(t)
+	for _, tt := range []struct {
+		name        string
+		contentType string
+		encode      func(message []byte) []byte
+		decode      func(t *testing.T, body []byte) []byte
+		request     []byte
+		value       func(t *testing.T, data []byte) string
+	}{
+		{
+			name:        "proto",
+			contentType: "application/grpc-web+proto",
+			request:     marshalString(t, "abc"),
+			value:       unmarshalString,
+		},
+		{
+			name:        "text",
+			contentType: "application/grpc-web-text",
+			encode:      func(body []byte) []byte { return []byte(base64.StdEncoding.EncodeToString(body)) },
+			// 响应按段编码，各段分别带有填充
+			decode: func(t *testing.T, body []byte) []byte {
+				data, err := io.ReadAll(&base64Reader{r: bufio.NewReader(bytes.NewReader(body))})
+				if err != nil {
+					t.Fatal(err)
+				}
+				return data
+			},
+			request: marshalString(t, "abc"),
+			value:   unmarshalString,
+		},
+		{
+			name:        "json",
+			contentType: "application/grpc-web+json",
+			request:     []byte(`"abc"`),
+			value: func(t *testing.T, data []byte) string {
+				var value string
+				if err := json.Unmarshal(data, &value); err != nil {
+					t.Fatal(err)
+				}
+				return value
+			},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			body := grpcFrame(tt.request)
+			if tt.encode != nil {
+				body = tt.encode(body)
+			}
+			resp := webPost(t, client, echoServerStream, tt.contentType, nil, body)
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("status %d", resp.StatusCode)
+			}
+			data := readBody(t, resp)
+			if tt.decode != nil {
+				data = tt.decode(t, data)
+			}
+			frames := readFrames(t, data)
+			if len(frames) != 4 {
+				t.Fatalf("got %d frames, want 3 messages and trailers", len(frames))
+			}
+			var got string
+			for _, frame := range frames[:3] {
+				got += tt.value(t, frame.data)
+			}
+			if got != "abc" {
+				t.Fatalf("messages %q, want %q", got, "abc")
+			}
+			trailer := frames[3]
+			if trailer.flag != webFrameTrailer || !strings.Contains(string(trailer.data), "grpc-status: 0\r\n") {
+				t.Fatalf("trailer %#x %q", trailer.flag, trailer.data)
+			}
+		})
+	}
+}
+
+func TestWebGrpcWebErrorTrailers(t *testing.T) {
+	client := startWebServer(t)
+	resp := webPost(t, client, echoUnary, "application/grpc-web+proto", nil, grpcFrame(marshalString(t, "error:bad input")))
+	frames := readFrames(t, readBody(t, resp))
+	if len(frames) != 1 || frames[0].flag != webFrameTrailer {
+		t.Fatalf("frames = %q, want only trailers", frames)
+	}
+	trailer := string(frames[0].data)
+	if !strings.Contains(trailer, "grpc-status: 3\r\n") || !strings.Contains(trailer, "grpc-message: bad input\r\n") &&
+		!strings.Contains(trailer, "grpc-message: bad%20input\r\n") {
+		t.Fatalf("trailer %q", trailer)
+	}
+}
+
+func TestWebConnectStream(t *testing.T) {
+	client := startWebServer(t)
+	header := http.Header{"Connect-Content-Encoding": {"gzip"}}
+	request := grpcFrame(gzipBytes(t, []byte(`"error:ab"`)))
+	request[0] = 1
+	resp := webPost(t, client, echoServerStream, "application/connect+json", header, request)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status %d", resp.StatusCode)
+	}
+	frames := readFrames(t, readBody(t, resp))
+	// 每个字符一条消息，最后是带错误的结束帧
+	if len(frames) != len("error:ab")+1 {
+		t.Fatalf("got %d frames", len(frames))
+	}
+	if got := string(frames[0].data); got != `"e"` {
+		t.Fatalf("first message %s", got)
+	}
+	end := frames[len(frames)-1]
+	if end.flag != connectFrameEndStream {
+		t.Fatalf("end flag %#x", end.flag)
+	}
+	var endStream struct {
+		Error *connectError `json:"error"`
+	}
+	if err := json.Unmarshal(end.data, &endStream); err != nil {
+		t.Fatal(err)
+	}
+	if endStream.Error == nil || endStream.Error.Code != "invalid_argument" || endStream.Error.Message != "ab" {
+		t.Fatalf("end stream %s", end.data)
+	}
+}
+
+func TestWebConnectStreamTooLarge(t *testing.T) {
+	client := startWebServer(t)
+	large := []byte(`"` + strings.Repeat("a", 4*webTestMaxRecvMsgSize) + `"`)
+	request := grpcFrame(gzipBytes(t, large))
+	request[0] = 1
+	resp := webPost(t, client, echoServerStream, "application/connect+json", http.Header{"Connect-Content-Encoding": {"gzip"}}, request)
+	frames := readFrames(t, readBody(t, resp))
+	end := frames[len(frames)-1]
+	if end.flag != connectFrameEndStream || !strings.Contains(string(end.data), `"resource_exhausted"`) {
+		t.Fatalf("end stream %#x %s", end.flag, end.data)
+	}
+}
+
+func TestWebCORS(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    WebOptions
+		origin  string
+		want    string
+		wantErr bool
+	}{
+		{name: "wildcard", opts: WebOptions{AllowedOrigins: []string{"*"}}, origin: "https://a.example.com", want: "*"},
+		{name: "explicit", opts: WebOptions{AllowedOrigins: []string{"https://a.example.com"}}, origin: "https://A.example.com", want: "https://A.example.com"},
+		{name: "not allowed", opts: WebOptions{AllowedOrigins: []string{"https://a.example.com"}}, origin: "https://b.example.com"},
+		{name: "credentials explicit", opts: WebOptions{AllowedOrigins: []string{"https://a.example.com"}, AllowCredentials: true}, origin: "https://a.example.com", want: "https://a.example.com"},
+		{name: "credentials wildcard", opts: WebOptions{AllowedOrigins: []string{"https://a.example.com", "*"}, AllowCredentials: true}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := NewServerOptions(WithServerWeb(tt.opts))
+			if err := opts.validateTransport(); (err != nil) != tt.wantErr {
+				t.Fatalf("validateTransport() = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got := opts.Web.allowedOrigin(tt.origin); got != tt.want {
+				t.Fatalf("allowedOrigin(%q) = %q, want %q", tt.origin, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGrpcTimeout(t *testing.T) {
+	tests := []struct {
+		ms   uint64
+		want string
+	}{
+		{ms: 0, want: "0m"},
+		{ms: 1500, want: "1500m"},
+		{ms: 99999999, want: "99999999m"},
+		{ms: 100000000, want: "100000S"},
+		{ms: 100000001, want: "100001S"},
+		{ms: 9999999999, want: "10000000S"},
+		{ms: 1 << 62, want: "99999999H"},
+	}
+	for _, tt := range tests {
+		if got := grpcTimeout(tt.ms); got != tt.want {
+			t.Fatalf("grpcTimeout(%d) = %q, want %q", tt.ms, got, tt.want)
+		}
+	}
+}
+
+func TestWebConnectTimeout(t *testing.T) {
+	client := startWebServer(t)
+	// Connect 允许10位的超时，转换后仍是合法的 grpc-timeout
+	header := http.Header{"Connect-Timeout-Ms": {"9999999999"}}
+	resp := webPost(t, client, echoUnary, "application/json", header, []byte(`"hello"`))
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status %d: %s", resp.StatusCode, readBody(t, resp))
+	}
+	header = http.Header{"Connect-Timeout-Ms": {"-1"}}
+	resp = webPost(t, client, echoUnary, "application/json", header, []byte(`"hello"`))
+	if resp.StatusCode == http.StatusOK {
+		t.Fatal("negative timeout was accepted")
+	}
+}